@@ -0,0 +1,127 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+)
+
+// CleuraAPIError is returned by every CleuraClient method when the Cleura API answers
+// with an unexpected status code. It carries the request and the error reported by the API.
+type CleuraAPIError struct {
+	Method      string
+	Path        string
+	StatusCode  int
+	Code        int
+	Message     string
+	Description string
+}
+
+func (e *CleuraAPIError) Error() string {
+	msg := fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Description != "" && e.Description != e.Message {
+		msg += ": " + e.Description
+	}
+	return fmt.Sprintf("%s (%s %s)", msg, e.Method, e.Path)
+}
+
+// checkResponse returns nil if the response has one of the expected status codes,
+// otherwise the body is consumed and returned as a *CleuraAPIError.
+func checkResponse(resp *http.Response, expected ...int) error {
+	if slices.Contains(expected, resp.StatusCode) {
+		return nil
+	}
+	return newCleuraAPIError(resp)
+}
+
+// newCleuraAPIError reads and closes the response body and builds a *CleuraAPIError from it.
+func newCleuraAPIError(resp *http.Response) *CleuraAPIError {
+	defer resp.Body.Close()
+	apiErr := &CleuraAPIError{StatusCode: resp.StatusCode}
+	if resp.Request != nil {
+		apiErr.Method = resp.Request.Method
+		apiErr.Path = resp.Request.URL.Path
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return apiErr
+	}
+	details := apiError{}
+	if json.Unmarshal(body, &details) == nil && (details.Error.Message != "" || details.Error.Code != 0) {
+		apiErr.Code = details.Error.Code
+		apiErr.Message = details.Error.Message
+		apiErr.Description = details.Error.Description
+		return apiErr
+	}
+	// Not a Cleura error document, keep the start of the body for the diagnostic
+	text := strings.TrimSpace(string(body))
+	if len(text) > 200 {
+		text = text[:200] + "..."
+	}
+	apiErr.Message = text
+	return apiErr
+}
+
+// IsNotFound reports whether err is a *CleuraAPIError for an object that does not exist. Some
+// Cleura endpoints pass on Keystone errors with another status code, but keep the Keystone code in
+// the error document. Any other error, a 400 included, does not mean the object is gone.
+func IsNotFound(err error) bool {
+	var apiErr *CleuraAPIError
+	return errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusNotFound || apiErr.Code == http.StatusNotFound)
+}
+
+// IsConflict reports whether err is a *CleuraAPIError for an object that already exists.
+func IsConflict(err error) bool {
+	var apiErr *CleuraAPIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict
+}
+
+// IsPermissionDenied reports whether err is a *CleuraAPIError caused by missing credentials or permissions.
+func IsPermissionDenied(err error) bool {
+	var apiErr *CleuraAPIError
+	return errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden)
+}
+
+// IsCancelled reports whether err was caused by Terraform cancelling the operation, which
+// happens when the user interrupts a plan or apply.
+func IsCancelled(ctx context.Context, err error) bool {
+	return errors.Is(err, context.Canceled) || (err != nil && errors.Is(ctx.Err(), context.Canceled))
+}
+
+// addCancelledError adds the diagnostic used for every interrupted operation and reports whether
+// err was caused by a cancellation or by the operation timing out, in which case the caller should
+// return without writing state.
+func addCancelledError(ctx context.Context, diags *diag.Diagnostics, operation string, err error) bool {
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		diags.AddError("Operation timed out", fmt.Sprintf("%s did not complete within %s. Increase the timeout in the timeouts block of the resource if the Cleura API needs more time.", operation, operationElapsed(ctx)))
+		return true
+	}
+	if !IsCancelled(ctx, err) {
+		return false
+	}
+	diags.AddError("Operation cancelled", fmt.Sprintf("%s was cancelled before it completed.", operation))
+	return true
+}
+
+// removeDisappeared handles a Read that found the object deleted outside Terraform. It is removed
+// from state so it is created again, unless fail_on_unexpected_disappearance is set in which case
+// the read fails and the state is kept.
+func (c *CleuraClient) removeDisappeared(ctx context.Context, resp *resource.ReadResponse, summary string) {
+	if c.FailOnUnexpectedDisappearance {
+		resp.Diagnostics.AddError(summary, "It was not found in Cleura and fail_on_unexpected_disappearance is set, so it is kept in state. Run terraform state rm if it was deleted on purpose, or unset fail_on_unexpected_disappearance to create it again.")
+		return
+	}
+	resp.State.RemoveResource(ctx)
+	resp.Diagnostics.AddWarning(summary, "New resource will be created")
+}
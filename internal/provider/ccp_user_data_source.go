@@ -138,6 +138,9 @@ func (c *ccpUserDataSource) Read(ctx context.Context, req datasource.ReadRequest
 	}
 	result, err := c.Client.GetCCPUser(ctx, userData.Name.ValueString())
 	if err != nil {
+		if addCancelledError(ctx, &resp.Diagnostics, "Reading CCP user "+userData.Name.ValueString(), err) {
+			return
+		}
 		resp.Diagnostics.AddError(
 			"Failed to get user",
 			err.Error(),
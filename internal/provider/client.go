@@ -7,21 +7,86 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
-	"github.com/sethvargo/go-password/password"
 )
 
+// CleuraClient is shared by all resources and data sources, which Terraform calls concurrently.
+// The token is only accessed through getToken and setToken.
 type CleuraClient struct {
-	Token    string
 	User     string
 	Password string
 	Url      string
 	Client   *http.Client
+	// DomainId is the default domain of OpenStack calls, resources and data sources may override it
 	DomainId string
+	// OTP or TOTPSecret answer the two-factor challenge when the account requires it. A one-time
+	// code can only be used for the first login, a secret also allows logging in again.
+	OTP        string
+	TOTPSecret string
+	// FailOnUnexpectedDisappearance makes Read fail instead of removing objects that are not found
+	FailOnUnexpectedDisappearance bool
+	// externalToken is set when the token was issued outside the provider and can not be renewed
+	externalToken bool
+	// tokenMu guards token, loginMu makes sure only one re-login happens at a time
+	token   string
+	tokenMu sync.RWMutex
+	loginMu sync.Mutex
+	// roles and projects cache the listings used to validate plans, so planning many users in a
+	// domain lists them once rather than once per user
+	roles    domainCache[openstackRoleJson]
+	projects domainCache[openstackProjectResourceJson]
 }
+
+// NewCleuraClient returns a client whose connections are pooled and reused by every request.
+// requestTimeout limits how long a single request may take until its response body has been read,
+// every retry gets the full timeout.
+func NewCleuraClient(user, password, url, domainId string, requestTimeout time.Duration, retry retryPolicy) *CleuraClient {
+	client := &CleuraClient{
+		User:     user,
+		Password: password,
+		Url:      url,
+		DomainId: domainId,
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	pooled := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   32,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	client.Client = &http.Client{
+		Transport: &authTransport{
+			client: client,
+			base: &retryTransport{
+				base:   &timeoutTransport{base: pooled, timeout: requestTimeout},
+				policy: retry,
+			},
+		},
+	}
+	return client
+}
+
+// domainFor returns the domain_id set on a resource or data source, or the domain_id of the
+// provider when it is not set.
+func (c *CleuraClient) domainFor(domainId types.String) string {
+	if domainId.IsNull() || domainId.IsUnknown() || domainId.ValueString() == "" {
+		return c.DomainId
+	}
+	return domainId.ValueString()
+}
+
 type CleuraAuth struct {
 	Auth CleuraAuthInfo `json:"auth"`
 }
@@ -32,61 +97,129 @@ type CleuraAuthInfo struct {
 type CleuraAuthResponse struct {
 	Result string `json:"result"`
 	Token  string `json:"token"`
+	// Set when the result is twofactor_required
+	Type         string `json:"type"`
+	Verification string `json:"verification"`
+}
+type CleuraTwoFactorRequest struct {
+	Request CleuraTwoFactorInfo `json:"request2fa"`
+}
+type CleuraTwoFactorInfo struct {
+	Login        string `json:"login"`
+	Verification string `json:"verification"`
+	Code         int    `json:"code"`
 }
 
 func (c *CleuraClient) Login(ctx context.Context) error {
 	tflog.Trace(ctx, "Login was called", nil)
-	login_marshalled, err := json.Marshal(CleuraAuth{CleuraAuthInfo{Username: c.User, Password: c.Password}})
+	authToken := CleuraAuthResponse{}
+	err := c.do(ctx, http.MethodPost, "auth/v1/tokens", CleuraAuth{CleuraAuthInfo{Username: c.User, Password: c.Password}}, &authToken, 200)
 	if err != nil {
-		tflog.Error(ctx, "Marshalling of login info failed", nil)
-		tflog.Error(ctx, err.Error())
 		return err
 	}
+	if authToken.Result == "twofactor_required" {
+		authToken, err = c.verifyTwoFactor(ctx, authToken)
+		if err != nil {
+			tflog.Error(ctx, fmt.Sprintf("Two-factor verification failed, error: %s", err.Error()), nil)
+			return err
+		}
+	}
+	if authToken.Result != "login_ok" {
+		tflog.Error(ctx, fmt.Sprintf("Response was not login_ok, response was: %s", authToken.Result), nil)
+		return fmt.Errorf(fmt.Sprintf("Authentication result was not login_ok. Result was %s", authToken.Result))
+	}
+	c.setToken(authToken.Token)
+	tflog.Trace(ctx, "Login complete!", nil)
+	return nil
+}
 
-	buffer := bytes.NewBuffer(login_marshalled)
-	response, err := http.Post(c.Url+"/auth/v1/tokens", "application/json", buffer)
-	// req, err := http.NewRequest(http.MethodPost,c.Url, buffer)
-	if err != nil {
-		tflog.Error(ctx, fmt.Sprintf("Failed to execute http post for login, error: %s", err.Error()), nil)
-		return err
+// verifyTwoFactor completes a login that was answered with a two-factor challenge. The code is
+// computed from TOTPSecret when set, otherwise the one-time code in OTP is used.
+func (c *CleuraClient) verifyTwoFactor(ctx context.Context, challenge CleuraAuthResponse) (CleuraAuthResponse, error) {
+	tflog.Debug(ctx, fmt.Sprintf("Two-factor authentication of type %s required", challenge.Type))
+	code := c.OTP
+	if c.TOTPSecret != "" {
+		var err error
+		code, err = totpCode(c.TOTPSecret, time.Now())
+		if err != nil {
+			return CleuraAuthResponse{}, err
+		}
 	}
-	defer response.Body.Close()
-	reader, err := io.ReadAll(response.Body)
+	if code == "" {
+		return CleuraAuthResponse{}, fmt.Errorf("two-factor authentication is enabled for %s, set totp_secret, CLEURA_TOTP_SECRET or CLEURA_OTP", c.User)
+	}
+	numeric, err := strconv.Atoi(strings.TrimSpace(code))
 	if err != nil {
-		tflog.Error(ctx, fmt.Sprintf("Failed to read response body after login request, error: %s", err.Error()), nil)
-		return err
+		return CleuraAuthResponse{}, fmt.Errorf("two-factor code must be numeric")
 	}
-	authToken := CleuraAuthResponse{}
-	err = json.Unmarshal(reader, &authToken)
+	payload := CleuraTwoFactorRequest{CleuraTwoFactorInfo{Login: c.User, Verification: challenge.Verification, Code: numeric}}
+	result := CleuraAuthResponse{}
+	if err := c.do(ctx, http.MethodPost, "auth/v1/tokens/verify2fa", payload, &result, 200); err != nil {
+		return CleuraAuthResponse{}, err
+	}
+	return result, nil
+}
+func (c *CleuraClient) getToken() string {
+	c.tokenMu.RLock()
+	defer c.tokenMu.RUnlock()
+	return c.token
+}
+func (c *CleuraClient) setToken(token string) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.token = token
+}
+
+// useToken makes the client authenticate with a token issued outside the provider instead of logging in.
+func (c *CleuraClient) useToken(token string) {
+	c.setToken(token)
+	c.externalToken = true
+}
+
+// ValidateToken checks that the Cleura API accepts the token with a cheap authenticated request.
+// Only a rejected token is an error, missing permissions for the request itself are not.
+func (c *CleuraClient) ValidateToken(ctx context.Context) error {
+	resp, err := c.request(ctx, http.MethodGet, fmt.Sprintf("accesscontrol/v1/openstack/%s/roles", c.DomainId), nil)
 	if err != nil {
-		tflog.Error(ctx, fmt.Sprintf("Failed to unmarshal response body into CleuraAuthResponse struct, error: %s", err.Error()), nil)
 		return err
 	}
-	if authToken.Result != "login_ok" {
-		tflog.Error(ctx, fmt.Sprintf("Response was not login_ok, response was: %s", authToken.Result), nil)
-		return fmt.Errorf(fmt.Sprintf("Authentication result was not login_ok. Result was %s", authToken.Result))
+	if isTokenExpired(resp) {
+		return newCleuraAPIError(resp)
 	}
-	c.Token = authToken.Token
-	tflog.Trace(context.Background(), "Login complete!", nil)
+	closeBody(resp)
 	return nil
 }
-func (c *CleuraClient) GetUser(ctx context.Context, user string) (openstackUserDatasourceModel, error) {
-	apiPath := fmt.Sprintf("accesscontrol/v1/openstack/%s/users/%s", c.DomainId, user)
-	cleuraUser := openstackUserDatasourceModelJson{}
-	result, err := c.get(apiPath)
-	if err != nil {
-		tflog.Error(ctx, fmt.Sprintf("Error occurred when executing get, error: %s", err.Error()))
-		return openstackUserDatasourceModel{}, err
+
+// relogin fetches a new token unless another request already replaced the expired one.
+func (c *CleuraClient) relogin(ctx context.Context, expiredToken string) error {
+	if c.externalToken {
+		return fmt.Errorf("the token was issued outside the provider and can not be renewed, issue a new token")
 	}
-	resultByteArray, err := io.ReadAll(result.Body)
-	result.Body.Close()
-	if err != nil {
-		tflog.Error(ctx, fmt.Sprintf("Failed to read result into byte array, error: %s", err.Error()), nil)
-		return openstackUserDatasourceModel{}, err
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+	if c.getToken() != expiredToken {
+		return nil
 	}
-	err = json.Unmarshal(resultByteArray, &cleuraUser)
-	if err != nil {
-		tflog.Error(ctx, fmt.Sprintf("Failed to unmarshal byte array into CleuraUser struct, error: %s", err.Error()), nil)
+	return c.Login(ctx)
+}
+
+// RevokeToken invalidates the current token so it can not be used after the provider has finished.
+func (c *CleuraClient) RevokeToken(ctx context.Context) error {
+	// A token issued elsewhere may still be in use by whoever issued it
+	if c.getToken() == "" || c.externalToken {
+		return nil
+	}
+	if err := c.do(ctx, http.MethodDelete, "auth/v1/tokens", nil, nil, 200, 204); err != nil {
+		return err
+	}
+	c.setToken("")
+	tflog.Trace(ctx, "Token revoked", nil)
+	return nil
+}
+func (c *CleuraClient) GetUser(ctx context.Context, domainId string, user string) (openstackUserDatasourceModel, error) {
+	apiPath := fmt.Sprintf("accesscontrol/v1/openstack/%s/users/%s", domainId, user)
+	cleuraUser := openstackUserDatasourceModelJson{}
+	if err := c.do(ctx, http.MethodGet, apiPath, nil, &cleuraUser, 200); err != nil {
 		return openstackUserDatasourceModel{}, err
 	}
 
@@ -98,6 +231,9 @@ func (c *CleuraClient) GetUser(ctx context.Context, user string) (openstackUserD
 		Enabled:          types.BoolValue(cleuraUser.Enabled),
 		Description:      types.StringValue(cleuraUser.Description),
 	}
+	if len(cleuraUser.DomainId) == 0 {
+		response.DomainId = types.StringValue(domainId)
+	}
 	for _, proj := range cleuraUser.Projects {
 		var roles []openstackRole
 		for _, role := range proj.Roles {
@@ -116,42 +252,14 @@ func (c *CleuraClient) GetUser(ctx context.Context, user string) (openstackUserD
 	return response, nil
 
 }
-func (c *CleuraClient) DeleteUser(ctx context.Context, user string) error {
-	apiPath := fmt.Sprintf("accesscontrol/v1/openstack/%s/users/%s", c.DomainId, user)
-	resp, err := c.delete(apiPath)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode != 204 {
-		apiErr := &apiError{}
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return err
-		}
-		json.Unmarshal(body, apiErr)
-		errMsg := fmt.Sprintf("failed to delete user, error: %+v", body)
-		tflog.Error(ctx, errMsg)
-		return errors.New(errMsg)
-	}
-	return nil
+func (c *CleuraClient) DeleteUser(ctx context.Context, domainId string, user string) error {
+	apiPath := fmt.Sprintf("accesscontrol/v1/openstack/%s/users/%s", domainId, user)
+	return c.do(ctx, http.MethodDelete, apiPath, nil, nil, 204)
 }
-func (c *CleuraClient) GetUserResource(ctx context.Context, user string) (openstackUserResourceModel, error) {
-	apiPath := fmt.Sprintf("accesscontrol/v1/openstack/%s/users/%s", c.DomainId, user)
+func (c *CleuraClient) GetUserResource(ctx context.Context, domainId string, user string) (openstackUserResourceModel, error) {
+	apiPath := fmt.Sprintf("accesscontrol/v1/openstack/%s/users/%s", domainId, user)
 	cleuraUser := openstackUserDatasourceModelJson{}
-	result, err := c.get(apiPath)
-	if err != nil {
-		tflog.Error(ctx, fmt.Sprintf("Error occurred when executing get, error: %s", err.Error()))
-		return openstackUserResourceModel{}, err
-	}
-	resultByteArray, err := io.ReadAll(result.Body)
-	result.Body.Close()
-	if err != nil {
-		tflog.Error(ctx, fmt.Sprintf("Failed to read result into byte array, error: %s", err.Error()), nil)
-		return openstackUserResourceModel{}, err
-	}
-	err = json.Unmarshal(resultByteArray, &cleuraUser)
-	if err != nil {
-		tflog.Error(ctx, fmt.Sprintf("Failed to unmarshal byte array into CleuraUser struct, error: %s", err.Error()), nil)
+	if err := c.do(ctx, http.MethodGet, apiPath, nil, &cleuraUser, 200); err != nil {
 		return openstackUserResourceModel{}, err
 	}
 	response := openstackUserResourceModel{
@@ -161,6 +269,12 @@ func (c *CleuraClient) GetUserResource(ctx context.Context, user string) (openst
 		// DefaultProjectId: types.StringValue(cleuraUser.DefaultProjectId),
 		Enabled: types.BoolValue(cleuraUser.Enabled),
 		// Description:      types.StringValue(cleuraUser.Description),
+		// The API never returns the password, the resource keeps it from state
+		Password:        types.StringNull(),
+		PasswordKeepers: types.MapNull(types.StringType),
+	}
+	if len(cleuraUser.DomainId) == 0 {
+		response.DomainId = types.StringValue(domainId)
 	}
 	if len(cleuraUser.DefaultProjectId) == 0 {
 		response.DefaultProjectId = types.StringNull()
@@ -172,8 +286,10 @@ func (c *CleuraClient) GetUserResource(ctx context.Context, user string) (openst
 	} else {
 		response.Description = types.StringValue(cleuraUser.Description)
 	}
+	// projects and roles are required, an empty collection must not be written as null
+	response.Projects = []openstackUserCreateProject{}
 	for _, proj := range cleuraUser.Projects {
-		var roles []string
+		roles := []string{}
 		for _, role := range proj.Roles {
 			roles = append(roles, role.Name)
 		}
@@ -186,29 +302,10 @@ func (c *CleuraClient) GetUserResource(ctx context.Context, user string) (openst
 	}
 	return response, nil
 }
-func (c *CleuraClient) DoesUserExist(ctx context.Context, user string) (bool, error) {
-	apiPath := fmt.Sprintf("accesscontrol/v1/openstack/%s/users/%s", c.DomainId, user)
-	result, err := c.get(apiPath)
-	if err != nil {
-		tflog.Error(ctx, fmt.Sprintf("Error occurred when executing get, error: %s", err.Error()))
-		return false, err
-	}
-	if result.StatusCode != 200 {
-		if result.StatusCode == 400 {
-			return false, nil
-		}
-		return false, fmt.Errorf("return code from cleura API is: %d", result.StatusCode)
-	}
-	return true, nil
-}
 func (c *CleuraClient) CreateUser(ctx context.Context, model openstackUserResourceModel) (openstackUserCreatedModel, error) {
 	apiPath := fmt.Sprintf("accesscontrol/v1/openstack/%s/users", model.DomainId.ValueString())
 	payload := createOpenstackUser{}
-	pw, err := password.Generate(12, 2, 0, false, true)
-	if err != nil {
-		return openstackUserCreatedModel{}, err
-	}
-	payload.User = createOpenstackUserInfo{Name: model.Name.ValueString(), Password: pw, Description: model.Description.ValueString()}
+	payload.User = createOpenstackUserInfo{Name: model.Name.ValueString(), Password: model.Password.ValueString(), Description: model.Description.ValueString()}
 	projectList := make([]openstackUserCreateProject, 0)
 	for _, p := range model.Projects {
 		projectList = append(projectList, openstackUserCreateProject{Id: p.Id, Roles: p.Roles})
@@ -218,194 +315,120 @@ func (c *CleuraClient) CreateUser(ctx context.Context, model openstackUserResour
 	// if err != nil {
 	// 	return err
 	// }
-	result, err := c.post(payload, apiPath)
-	if err != nil {
-		return openstackUserCreatedModel{}, err
-	}
-	msg, err := io.ReadAll(result.Body)
-	if err != nil {
+	// Only send the create again if the user was not created by the failed attempt
+	retryCtx := withRetryCheck(ctx, func(ctx context.Context) (bool, error) {
+		_, found, err := c.FindUserByName(ctx, model.DomainId.ValueString(), model.Name.ValueString())
+		return !found, err
+	})
+	created := openstackUserCreatedModel{}
+	if err := c.do(retryCtx, http.MethodPost, apiPath, payload, &created, 201); err != nil {
 		return openstackUserCreatedModel{}, err
 	}
-	if result.StatusCode != 201 {
-		apiErr := &apiError{}
-		json.Unmarshal(msg, apiErr)
-		tflog.Error(ctx, fmt.Sprintf("%+v", result))
-		return openstackUserCreatedModel{}, errors.New("return code was not 200")
-	}
-	created := &openstackUserCreatedModel{}
-	json.Unmarshal(msg, created)
-	return *created, nil
+	return created, nil
 
 }
-func (c *CleuraClient) post(payload interface{}, apiPath string) (*http.Response, error) {
-	url := fmt.Sprintf("%s/%s", c.Url, apiPath)
-	marshaled_payload, err := json.Marshal(payload)
-	if err != nil {
-		return nil, err
-	}
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(marshaled_payload))
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-AUTH-LOGIN", c.User)
-	req.Header.Set("X-AUTH-TOKEN", c.Token)
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	// defer resp.Body.Close()
-	return resp, nil
-}
-func (c *CleuraClient) get(apiPath string) (*http.Response, error) {
-	url := fmt.Sprintf("%s/%s", c.Url, apiPath)
-	req, err := http.NewRequest("GET", url, nil)
-	req.Header.Set("X-AUTH-LOGIN", c.User)
-	req.Header.Set("X-AUTH-TOKEN", c.Token)
-	if err != nil {
-		return nil, err
-	}
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	return resp, nil
 
-}
-func (c *CleuraClient) delete(apiPath string) (*http.Response, error) {
-	url := fmt.Sprintf("%s/%s", c.Url, apiPath)
-	req, err := http.NewRequest("DELETE", url, nil)
-	req.Header.Set("X-AUTH-LOGIN", c.User)
-	req.Header.Set("X-AUTH-TOKEN", c.Token)
-	if err != nil {
-		return nil, err
-	}
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		return nil, err
+// FindUserByName looks up an OpenStack user by name in the given domain and returns its ID.
+func (c *CleuraClient) FindUserByName(ctx context.Context, domainId string, name string) (string, bool, error) {
+	apiPath := fmt.Sprintf("accesscontrol/v1/openstack/%s/users", domainId)
+	var users []openstackUserDatasourceModelJson
+	if err := c.do(ctx, http.MethodGet, apiPath, nil, &users, 200); err != nil {
+		return "", false, err
+	}
+	for _, u := range users {
+		if u.Name == name {
+			return u.Id, true, nil
+		}
 	}
-	return resp, nil
+	return "", false, nil
 }
-func (c *CleuraClient) put(payload interface{}, apiPath string) (*http.Response, error) {
-	url := fmt.Sprintf("%s/%s", c.Url, apiPath)
-	marshaled_payload, err := json.Marshal(payload)
-	if err != nil {
-		return nil, err
+
+// request sends a request to the Cleura API with payload encoded as JSON, nil sends no body.
+func (c *CleuraClient) request(ctx context.Context, method string, apiPath string, payload any) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		marshaled, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(marshaled)
 	}
-	req, err := http.NewRequest("PUT", url, bytes.NewBuffer(marshaled_payload))
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s/%s", c.Url, apiPath), body)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-AUTH-LOGIN", c.User)
-	req.Header.Set("X-AUTH-TOKEN", c.Token)
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		return nil, err
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
 	}
-	// defer resp.Body.Close()
-	return resp, nil
+	return c.Client.Do(req)
 }
-func (c *CleuraClient) AddUserToProjectRole(ctx context.Context, user string, projectId string, projectRole string) error {
-	apiUrl := fmt.Sprintf("accesscontrol/v1/openstack/%s/users/%s/projects", c.DomainId, user)
-	roles := []string{projectRole}
-	ass := openstackProjectAssignment{ProjectId: projectId, Roles: roles}
-	assignments := []openstackProjectAssignment{ass}
-	resp, err := c.post(openstackProjectUpdate{Projects: assignments}, apiUrl)
+
+// do sends a request and decodes the response into result unless it is nil. A status other than
+// expected is returned as a *CleuraAPIError. The response body is always drained and closed, so
+// the connection goes back to the pool.
+func (c *CleuraClient) do(ctx context.Context, method string, apiPath string, payload any, result any, expected ...int) error {
+	resp, err := c.request(ctx, method, apiPath, payload)
 	if err != nil {
+		tflog.Error(ctx, fmt.Sprintf("%s %s failed, error: %s", method, apiPath, err.Error()))
 		return err
 	}
-	if resp.StatusCode != 200 {
-		tflog.Error(ctx, fmt.Sprintf("status code returned is: %d", resp.StatusCode))
-		r, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return err
-		}
-		resp.Body.Close()
-		errMsg := &apiError{}
-		err = json.Unmarshal(r, errMsg)
-		if err != nil {
-			return err
+	defer closeBody(resp)
+	if err := checkResponse(resp, expected...); err != nil {
+		// A missing object is expected while reading, the caller decides whether it is an error
+		if IsNotFound(err) {
+			tflog.Debug(ctx, fmt.Sprintf("%s %s: %s", method, apiPath, err.Error()))
+		} else {
+			tflog.Error(ctx, fmt.Sprintf("%s %s failed, error: %s", method, apiPath, err.Error()))
 		}
-		errStr := fmt.Sprintf("error message from api is: %+v", errMsg)
-		tflog.Error(ctx, errStr)
-		return errors.New(errStr)
-	}
-	return nil
-}
-func (c *CleuraClient) RemoveUserFromProjectRole(ctx context.Context, user string, projectId string, role string) error {
-	apiUrl := fmt.Sprintf("accesscontrol/v1/openstack/%s/users/%s/projects/%s/%s", c.DomainId, user, projectId, role)
-	resp, err := c.delete(apiUrl)
-	if err != nil {
 		return err
 	}
-	if resp.StatusCode != 200 {
-		tflog.Error(ctx, fmt.Sprintf("status code returned is: %d", resp.StatusCode))
-		r, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return err
-		}
-		resp.Body.Close()
-		errMsg := &apiError{}
-		err = json.Unmarshal(r, errMsg)
-		if err != nil {
-			return err
-		}
-		errStr := fmt.Sprintf("error message from api is: %+v", errMsg)
-		tflog.Error(ctx, errStr)
-		return errors.New(errStr)
+	if result == nil {
+		return nil
+	}
+	// Not every response carries a body, the result is then left as it is
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil && !errors.Is(err, io.EOF) {
+		tflog.Error(ctx, fmt.Sprintf("Failed to decode the response of %s %s, error: %s", method, apiPath, err.Error()))
+		return err
 	}
 	return nil
+}
 
+// closeBody drains and closes a response body, the connection is only reused by the pool once the body has been read to the end.
+func closeBody(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	resp.Body.Close()
 }
-func (c *CleuraClient) AddUserToProject(projects openstackProjectUpdate) {
+func (c *CleuraClient) AddUserToProjectRole(ctx context.Context, domainId string, user string, projectId string, projectRole string) error {
+	apiUrl := fmt.Sprintf("accesscontrol/v1/openstack/%s/users/%s/projects", domainId, user)
+	roles := []string{projectRole}
+	ass := openstackProjectAssignment{ProjectId: projectId, Roles: roles}
+	assignments := []openstackProjectAssignment{ass}
+	return c.do(ctx, http.MethodPost, apiUrl, openstackProjectUpdate{Projects: assignments}, nil, 200)
 }
-func (c *CleuraClient) ToggleUserEnabled(ctx context.Context, user string, enabled bool) error {
-	url := fmt.Sprintf("accesscontrol/v1/openstack/%s/users/%s", c.DomainId, user)
-
-	resp, err := c.put(openstackUserUpdate{User: openstackUserUpdateProperties{Enabled: enabled}}, url)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode != 200 {
-		tflog.Error(ctx, fmt.Sprintf("status code returned is: %d", resp.StatusCode))
-		r, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return err
-		}
-		resp.Body.Close()
-		errMsg := &apiError{}
-		err = json.Unmarshal(r, errMsg)
-		if err != nil {
-			return err
-		}
-		errStr := fmt.Sprintf("error message from api is: %+v", errMsg)
-		tflog.Error(ctx, errStr)
-		return errors.New(errStr)
-	}
-	return nil
+func (c *CleuraClient) RemoveUserFromProjectRole(ctx context.Context, domainId string, user string, projectId string, role string) error {
+	apiUrl := fmt.Sprintf("accesscontrol/v1/openstack/%s/users/%s/projects/%s/%s", domainId, user, projectId, role)
+	return c.do(ctx, http.MethodDelete, apiUrl, nil, nil, 200)
+}
+func (c *CleuraClient) AddUserToProject(ctx context.Context, domainId string, user string, projects openstackProjectUpdate) error {
+	apiUrl := fmt.Sprintf("accesscontrol/v1/openstack/%s/users/%s/projects", domainId, user)
+	return c.do(ctx, http.MethodPost, apiUrl, projects, nil, 200)
+}
+func (c *CleuraClient) ToggleUserEnabled(ctx context.Context, domainId string, user string, enabled bool) error {
+	url := fmt.Sprintf("accesscontrol/v1/openstack/%s/users/%s", domainId, user)
+	return c.do(ctx, http.MethodPut, url, openstackUserUpdate{User: openstackUserUpdateProperties{Enabled: &enabled}}, nil, 200)
+}
+func (c *CleuraClient) SetUserPassword(ctx context.Context, domainId string, user string, password string) error {
+	url := fmt.Sprintf("accesscontrol/v1/openstack/%s/users/%s", domainId, user)
+	return c.do(ctx, http.MethodPut, url, openstackUserUpdate{User: openstackUserUpdateProperties{Password: password}}, nil, 200)
+}
+func (c *CleuraClient) SetUserDescription(ctx context.Context, domainId string, user string, description string) error {
+	url := fmt.Sprintf("accesscontrol/v1/openstack/%s/users/%s", domainId, user)
+	return c.do(ctx, http.MethodPut, url, openstackUserUpdate{User: openstackUserUpdateProperties{Description: &description}}, nil, 200)
 }
 func (c *CleuraClient) GetCCPUser(ctx context.Context, name string) (ccpUserDataSourceModel, error) {
 	apiPath := fmt.Sprintf("accesscontrol/v1/users/%s", name)
 	ccpUser := ccpUserJson{}
-	result, err := c.get(apiPath)
-	if err != nil {
-		tflog.Error(ctx, fmt.Sprintf("Error occurred when executing get, error: %s", err.Error()))
-		return ccpUserDataSourceModel{}, err
-	}
-	if result.StatusCode != 200 {
-		tflog.Error(ctx, fmt.Sprintf("return code was not 200, return code: %d when searching for user: %s", result.StatusCode, name))
-		return ccpUserDataSourceModel{}, fmt.Errorf("return code was not 200, return code: %d when searching for user: %s", result.StatusCode, name)
-	}
-	resultByteArray, err := io.ReadAll(result.Body)
-	result.Body.Close()
-	if err != nil {
-		tflog.Error(ctx, fmt.Sprintf("Failed to read result into byte array, error: %s", err.Error()), nil)
-		return ccpUserDataSourceModel{}, err
-	}
-	err = json.Unmarshal(resultByteArray, &ccpUser)
-	if err != nil {
-		tflog.Error(ctx, fmt.Sprintf("Failed to unmarshal byte array into CCPUser struct, error: %s", err.Error()), nil)
+	if err := c.do(ctx, http.MethodGet, apiPath, nil, &ccpUser, 200); err != nil {
 		return ccpUserDataSourceModel{}, err
 	}
 	response := ccpUserDataSourceModel{
@@ -426,18 +449,16 @@ func (c *CleuraClient) GetCCPUser(ctx context.Context, name string) (ccpUserData
 		Meta: types.StringValue(ccpUser.Privileges.Users.Meta),
 	}
 
-	// Uncomment when adding support for project privileges
-	// var osProjPrivileges []ccpProjectPrivileges
-	// for _, osp := range ccpUser.Privileges.OpenStack.ProjectPrivileges {
-	// 	projectPrivileges := ccpProjectPrivileges{
-	// 		ProjectId: types.StringValue(osp.ProjectId),
-	// 		DomainId:  types.StringValue(osp.DomainId),
-	// 		Type:      types.StringValue(osp.Type),
-	// 	}
-	// 	osProjPrivileges = append(osProjPrivileges, projectPrivileges)
-	// }
-	// osPrivileges := ccpOpenstackPrivileges{Type: types.StringValue(ccpUser.Privileges.OpenStack.Type), Meta: types.StringValue(ccpUser.Privileges.OpenStack.Meta), ProjectPrivileges: osProjPrivileges}
-	osPrivileges := ccpOpenstackPrivileges{Type: types.StringValue(ccpUser.Privileges.OpenStack.Type), Meta: types.StringValue(ccpUser.Privileges.OpenStack.Meta)}
+	var osProjPrivileges []ccpProjectPrivileges
+	for _, osp := range ccpUser.Privileges.OpenStack.ProjectPrivileges {
+		projectPrivileges := ccpProjectPrivileges{
+			ProjectId: types.StringValue(osp.ProjectId),
+			DomainId:  types.StringValue(osp.DomainId),
+			Type:      types.StringValue(osp.Type),
+		}
+		osProjPrivileges = append(osProjPrivileges, projectPrivileges)
+	}
+	osPrivileges := ccpOpenstackPrivileges{Type: types.StringValue(ccpUser.Privileges.OpenStack.Type), Meta: types.StringValue(ccpUser.Privileges.OpenStack.Meta), ProjectPrivileges: osProjPrivileges}
 	privileges := ccpPrivileges{
 		Users:     userPrivileges,
 		OpenStack: osPrivileges,
@@ -449,32 +470,15 @@ func (c *CleuraClient) GetCCPUser(ctx context.Context, name string) (ccpUserData
 func (c *CleuraClient) GetCCPUserResource(ctx context.Context, name string) (ccpUserResourceModel, error) {
 	apiPath := fmt.Sprintf("accesscontrol/v1/users/%s", name)
 	ccpUser := ccpUserJson{}
-	result, err := c.get(apiPath)
-	if err != nil {
-		tflog.Error(ctx, fmt.Sprintf("Error occurred when executing get, error: %s", err.Error()))
-		return ccpUserResourceModel{}, err
-	}
-	if result.StatusCode != 200 {
-		tflog.Error(ctx, fmt.Sprintf("return code was not 200, return code: %d when searching for user: %s", result.StatusCode, name))
-		return ccpUserResourceModel{}, fmt.Errorf("return code was not 200, return code: %d when searching for user: %s", result.StatusCode, name)
-	}
-	resultByteArray, err := io.ReadAll(result.Body)
-	result.Body.Close()
-	if err != nil {
-		tflog.Error(ctx, fmt.Sprintf("Failed to read result into byte array, error: %s", err.Error()), nil)
-		return ccpUserResourceModel{}, err
-	}
-	err = json.Unmarshal(resultByteArray, &ccpUser)
-	if err != nil {
-		tflog.Error(ctx, fmt.Sprintf("Failed to unmarshal byte array into CCPUser struct, error: %s", err.Error()), nil)
+	if err := c.do(ctx, http.MethodGet, apiPath, nil, &ccpUser, 200); err != nil {
 		return ccpUserResourceModel{}, err
 	}
 	response := ccpUserResourceModel{
 		Id:   types.StringValue(ccpUser.Id),
 		Name: types.StringValue(ccpUser.Name),
 		// Admin:          types.BoolValue(ccpUser.Admin),
-		FirstName: types.StringValue(ccpUser.FirstName),
-		LastName:  types.StringValue(ccpUser.LastName),
+		FirstName: types.StringNull(),
+		LastName:  types.StringNull(),
 		Email:     types.StringValue(ccpUser.Email),
 		// Language:  types.StringValue(ccpUser.Language),
 		// Currency:       &ccpCurrency{Id: types.StringValue(ccpUser.Currency.Id), Code: types.StringValue(ccpUser.Currency.Code), Name: types.StringValue(ccpUser.Currency.Name)},
@@ -482,26 +486,32 @@ func (c *CleuraClient) GetCCPUserResource(ctx context.Context, name string) (ccp
 		// TwoFactorLogin: ccpUser.TwoFactorLogin,
 		// IPRestrictions: ccpUser.IPRestrictions,
 	}
-	userPrivileges := ccpUserResourceUserPrivilege{
-		Type: types.StringValue(ccpUser.Privileges.Users.Type),
+	// Optional attributes are null rather than "" so imported state matches the configuration
+	if len(ccpUser.FirstName) > 0 {
+		response.FirstName = types.StringValue(ccpUser.FirstName)
 	}
-	// Uncomment when adding support for project privileges
-	// var osProjPrivileges []ccpUserResourceProjectPrivileges
-	// for _, osp := range ccpUser.Privileges.OpenStack.ProjectPrivileges {
-	// 	projectPrivileges := ccpUserResourceProjectPrivileges{
-	// 		ProjectId: types.StringValue(osp.ProjectId),
-	// 		DomainId:  types.StringValue(osp.DomainId),
-	// 		Type:      types.StringValue(osp.Type),
-	// 	}
-	// 	osProjPrivileges = append(osProjPrivileges, projectPrivileges)
-	// }
-	// osPrivileges := ccpUserResourceOpenstackPrivileges{Type: types.StringValue(ccpUser.Privileges.OpenStack.Type), ProjectPrivileges: osProjPrivileges}
-	osPrivileges := ccpUserResourceOpenstackPrivileges{Type: types.StringValue(ccpUser.Privileges.OpenStack.Type)}
-	privileges := ccpResourcePrivileges{
-		Users:     userPrivileges,
-		OpenStack: osPrivileges,
+	if len(ccpUser.LastName) > 0 {
+		response.LastName = types.StringValue(ccpUser.LastName)
+	}
+	var osProjPrivileges []ccpUserResourceProjectPrivileges
+	for _, osp := range ccpUser.Privileges.OpenStack.ProjectPrivileges {
+		projectPrivileges := ccpUserResourceProjectPrivileges{
+			ProjectId: types.StringValue(osp.ProjectId),
+			DomainId:  types.StringValue(osp.DomainId),
+			Type:      types.StringValue(osp.Type),
+		}
+		osProjPrivileges = append(osProjPrivileges, projectPrivileges)
+	}
+	privileges := ccpResourcePrivileges{}
+	if len(ccpUser.Privileges.Users.Type) > 0 {
+		privileges.Users = &ccpUserResourceUserPrivilege{Type: types.StringValue(ccpUser.Privileges.Users.Type)}
+	}
+	if len(ccpUser.Privileges.OpenStack.Type) > 0 || len(osProjPrivileges) > 0 {
+		privileges.OpenStack = &ccpUserResourceOpenstackPrivileges{Type: types.StringValue(ccpUser.Privileges.OpenStack.Type), ProjectPrivileges: osProjPrivileges}
+	}
+	if privileges.Users != nil || privileges.OpenStack != nil {
+		response.Privileges = &privileges
 	}
-	response.Privileges = &privileges
 	return response, nil
 }
 func (c *CleuraClient) CreateCCPUser(ctx context.Context, model ccpUserResourceModel) (ccpUserResourceModel, error) {
@@ -512,33 +522,7 @@ func (c *CleuraClient) CreateCCPUser(ctx context.Context, model ccpUserResourceM
 	// 	return ccpUserResourceModel{}, err
 	// }
 	// model.Password = types.StringValue(pw)
-	modelJson := ccpUserResourceModelJson{
-		Name:      model.Name.ValueString(),
-		Email:     model.Email.ValueString(),
-		FirstName: model.FirstName.ValueString(),
-		LastName:  model.LastName.ValueString(),
-	}
-	if model.Privileges == nil {
-		modelJson.Privileges = &ccpResourcePrivilegesJson{}
-	} else {
-		modelJson.Privileges = &ccpResourcePrivilegesJson{}
-		modelJson.Privileges.Users = ccpUserResourcePrivilegeJson{
-			Type: model.Privileges.Users.Type.ValueString(),
-			// Meta: model.Privileges.Users.Meta.ValueString(),
-		}
-		// Uncomment when adding support for project privileges
-		// var projectPrivileges []ccpUserResourceProjectPrivilegesJson
-		// if model.Privileges.OpenStack.ProjectPrivileges != nil {
-		// 	for _, p := range model.Privileges.OpenStack.ProjectPrivileges {
-		// 		projectPrivileges = append(projectPrivileges, ccpUserResourceProjectPrivilegesJson{ProjectId: p.ProjectId.ValueString(), DomainId: p.DomainId.ValueString(), Type: p.Type.ValueString()})
-		// 	}
-		// 	modelJson.Privileges.OpenStack.ProjectPrivileges = projectPrivileges
-		// }
-		modelJson.Privileges.OpenStack = ccpUserOpenstackPrivilegesJson{
-			Type: model.Privileges.OpenStack.Type.ValueString(),
-			// Meta: model.Privileges.OpenStack.Meta.ValueString(),
-		}
-	}
+	modelJson := getCCPUserJson(model)
 
 	// var result map[string]interface{}
 	// json.Unmarshal(payload, &result)
@@ -560,87 +544,148 @@ func (c *CleuraClient) CreateCCPUser(ctx context.Context, model ccpUserResourceM
 	// }
 
 	//"{\"user\":{\"name\":\"johan.testberg\",\"email\":\"johan.testberg@thernfrst.io\",\"firstname\":\"johan\",\"lastname\":\"testberg\",\"privileges\":{\"users\":{\"type\":\"\",\"meta\":\"\"},\"openstack\":{\"type\":\"\",\"meta\":\"\"}}}}"
-	resp, err := c.post(ccpUserCreateJson{User: modelJson}, apiPath)
-	if err != nil {
+	// Only send the create again if the user was not created by the failed attempt
+	retryCtx := withRetryCheck(ctx, func(ctx context.Context) (bool, error) {
+		exist, err := c.DoesCCPUserExist(ctx, model.Name.ValueString())
+		return !exist, err
+	})
+	created := ccpUserJson{}
+	if err := c.do(retryCtx, http.MethodPost, apiPath, ccpUserCreateJson{User: modelJson}, &created, 200); err != nil {
 		return ccpUserResourceModel{}, err
 	}
-	msg, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return ccpUserResourceModel{}, err
+	if created.Id == "" {
+		// Not every response carries the created user, look it up to learn its ID
+		existing, err := c.GetCCPUserResource(ctx, model.Name.ValueString())
+		if err != nil {
+			return ccpUserResourceModel{}, fmt.Errorf("user was created but could not be read back, error: %w", err)
+		}
+		created.Id = existing.Id.ValueString()
 	}
-	if resp.StatusCode == 409 {
-		tflog.Error(ctx, "the specified user already exists")
-		return ccpUserResourceModel{}, fmt.Errorf("the specified user already exists")
-	}
-	if resp.StatusCode != 200 {
-		// if err != nil {
-		// 	return ccpUserResourceModel{}, err
-		// }
-		apiErr := &apiError{}
-		json.Unmarshal(msg, apiErr)
-		tflog.Error(ctx, fmt.Sprintf("%+v", apiErr))
-		return ccpUserResourceModel{}, errors.New("return code was not 200")
-	}
-	created := &openstackUserCreatedModel{}
-	json.Unmarshal(msg, created)
+	model.Id = types.StringValue(created.Id)
 	return model, nil
 
 }
+
+// FindCCPUserById looks up a CCP user by its numeric ID and returns its login name.
+func (c *CleuraClient) FindCCPUserById(ctx context.Context, id string) (string, bool, error) {
+	var users []ccpUserJson
+	if err := c.do(ctx, http.MethodGet, "accesscontrol/v1/users", nil, &users, 200); err != nil {
+		return "", false, err
+	}
+	for _, u := range users {
+		if u.Id == id {
+			return u.Name, true, nil
+		}
+	}
+	return "", false, nil
+}
 func (c *CleuraClient) DoesCCPUserExist(ctx context.Context, user string) (bool, error) {
 	apiPath := fmt.Sprintf("accesscontrol/v1/users/%s", user)
-	result, err := c.get(apiPath)
+	err := c.do(ctx, http.MethodGet, apiPath, nil, nil, 200)
+	if IsNotFound(err) {
+		return false, nil
+	}
 	if err != nil {
-		tflog.Error(ctx, fmt.Sprintf("Error occurred when executing get, error: %s", err.Error()))
 		return false, err
 	}
-	if result.StatusCode != 200 {
-		if result.StatusCode == 400 {
-			return false, nil
-		}
-		return false, fmt.Errorf("return code from cleura API is: %d", result.StatusCode)
-	}
 	return true, nil
 }
 func (c *CleuraClient) UpdateCCPUser(ctx context.Context, resource ccpUserUpdate) error {
 	apiPath := fmt.Sprintf("accesscontrol/v1/users/%s", resource.User.Name)
-	result, err := c.put(resource, apiPath)
-	if err != nil {
-		return err
+	return c.do(ctx, http.MethodPut, apiPath, resource, nil, 200)
+}
+func (c *CleuraClient) DeleteCCPUser(ctx context.Context, user string) error {
+	apiPath := fmt.Sprintf("accesscontrol/v1/users/%s", user)
+	return c.do(ctx, http.MethodDelete, apiPath, nil, nil, 204)
+}
+func (c *CleuraClient) CreateProject(ctx context.Context, domainId string, project openstackProjectResourceJson) (openstackProjectResourceJson, error) {
+	apiPath := fmt.Sprintf("accesscontrol/v1/openstack/%s/projects", domainId)
+	created := openstackProjectResourceJson{}
+	if err := c.do(ctx, http.MethodPost, apiPath, openstackProjectRequestJson{Project: project}, &created, 200, 201); err != nil {
+		return openstackProjectResourceJson{}, err
+	}
+	c.projects.invalidate(domainId)
+	return created, nil
+}
+func (c *CleuraClient) GetProject(ctx context.Context, domainId string, projectId string) (openstackProjectResourceJson, error) {
+	apiPath := fmt.Sprintf("accesscontrol/v1/openstack/%s/projects/%s", domainId, projectId)
+	project := openstackProjectResourceJson{}
+	if err := c.do(ctx, http.MethodGet, apiPath, nil, &project, 200); err != nil {
+		return openstackProjectResourceJson{}, err
 	}
-	if result.StatusCode != 200 {
-		defer result.Body.Close()
-		tflog.Error(ctx, fmt.Sprintf("return code when updating CCP user was not 200, code was: %d", result.StatusCode))
-		msg, err := io.ReadAll(result.Body)
-		if err != nil {
-			tflog.Error(ctx, fmt.Sprintf("Failed to decode response body, error: %s", err.Error()))
-			return fmt.Errorf("failed to decode response body, error: %s", err.Error())
-		}
-		apiErr := &apiError{}
-		json.Unmarshal(msg, apiErr)
-		tflog.Error(ctx, fmt.Sprintf("%+v", apiErr))
-		return fmt.Errorf("error returned from API while updating user is: %+v", apiErr)
+	return project, nil
+}
+func (c *CleuraClient) UpdateProject(ctx context.Context, domainId string, projectId string, project openstackProjectResourceJson) error {
+	apiPath := fmt.Sprintf("accesscontrol/v1/openstack/%s/projects/%s", domainId, projectId)
+	return c.do(ctx, http.MethodPut, apiPath, openstackProjectRequestJson{Project: project}, nil, 200)
+}
+func (c *CleuraClient) DeleteProject(ctx context.Context, domainId string, projectId string) error {
+	apiPath := fmt.Sprintf("accesscontrol/v1/openstack/%s/projects/%s", domainId, projectId)
+	if err := c.do(ctx, http.MethodDelete, apiPath, nil, nil, 204); err != nil {
+		return err
 	}
+	c.projects.invalidate(domainId)
 	return nil
 }
-func (c *CleuraClient) CheckApiError(response *http.Response) {
+func (c *CleuraClient) ListProjects(ctx context.Context, domainId string) ([]openstackProjectResourceJson, error) {
+	apiPath := fmt.Sprintf("accesscontrol/v1/openstack/%s/projects", domainId)
+	var projects []openstackProjectResourceJson
+	if err := c.do(ctx, http.MethodGet, apiPath, nil, &projects, 200); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+func (c *CleuraClient) ListRoles(ctx context.Context, domainId string) ([]openstackRoleJson, error) {
+	apiPath := fmt.Sprintf("accesscontrol/v1/openstack/%s/roles", domainId)
+	var roles []openstackRoleJson
+	if err := c.do(ctx, http.MethodGet, apiPath, nil, &roles, 200); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
 
+// CachedRoles returns the roles of domainId, listing them only the first time.
+func (c *CleuraClient) CachedRoles(ctx context.Context, domainId string) ([]openstackRoleJson, error) {
+	return c.roles.get(ctx, domainId, c.ListRoles)
 }
-func (c *CleuraClient) DeleteCCPUser(ctx context.Context, user string) error {
-	apiPath := fmt.Sprintf("accesscontrol/v1/users/%s", user)
-	resp, err := c.delete(apiPath)
+
+// CachedProjects returns the projects of domainId, listing them only the first time or after a
+// project was created or deleted by the provider. Set refresh to list them again, for a project
+// created outside the provider since.
+func (c *CleuraClient) CachedProjects(ctx context.Context, domainId string, refresh bool) ([]openstackProjectResourceJson, error) {
+	if refresh {
+		c.projects.invalidate(domainId)
+	}
+	return c.projects.get(ctx, domainId, c.ListProjects)
+}
+
+// domainCache holds a listing per domain for the lifetime of the client.
+type domainCache[T any] struct {
+	mu     sync.Mutex
+	values map[string][]T
+}
+
+// get returns the cached listing of domainId, or calls list and caches the result. Concurrent
+// callers wait for a single listing.
+func (d *domainCache[T]) get(ctx context.Context, domainId string, list func(context.Context, string) ([]T, error)) ([]T, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if values, ok := d.values[domainId]; ok {
+		return values, nil
+	}
+	values, err := list(ctx, domainId)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if resp.StatusCode != 204 {
-		apiErr := &apiError{}
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return err
-		}
-		json.Unmarshal(body, apiErr)
-		errMsg := fmt.Sprintf("failed to delete user, error: %+v", body)
-		tflog.Error(ctx, errMsg)
-		return errors.New(errMsg)
+	if d.values == nil {
+		d.values = map[string][]T{}
 	}
-	return nil
+	d.values[domainId] = values
+	return values, nil
+}
+
+func (d *domainCache[T]) invalidate(domainId string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.values, domainId)
 }
@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRequestTimeoutCoversBody(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	_, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[{"id":`))
+		w.(http.Flusher).Flush()
+		// The body stalls after the headers were sent
+		<-release
+	}))
+	client := NewCleuraClient("user", "password", server.URL, "d", 200*time.Millisecond, retryPolicy{})
+	client.setToken("token")
+
+	start := time.Now()
+	_, err := client.ListProjects(context.Background(), "d")
+	var timeoutErr *requestTimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected a requestTimeoutError, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("request took %s, expected it to stop after the request timeout", elapsed)
+	}
+	if !isUnreachable(err) {
+		t.Error("a request timeout is not reported as an unreachable API")
+	}
+}
+
+func TestRequestTimeoutIsPerAttempt(t *testing.T) {
+	var attempts atomic.Int32
+	_, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		time.Sleep(150 * time.Millisecond)
+		writeJSON(w, http.StatusOK, []openstackRoleJson{})
+	}))
+	// The retry starts after the backoff, so with a timeout for both attempts together it would fail
+	client := NewCleuraClient("user", "password", server.URL, "d", 250*time.Millisecond, retryPolicy{MaxRetries: 1, MinWait: 150 * time.Millisecond, MaxWait: 150 * time.Millisecond})
+	client.setToken("token")
+	if _, err := client.ListRoles(context.Background(), "d"); err != nil {
+		t.Fatalf("the retry should get its own timeout: %s", err)
+	}
+}
+
+// TestConcurrentRelogin runs many requests in parallel while the token expires, run it with -race.
+func TestConcurrentRelogin(t *testing.T) {
+	var logins atomic.Int32
+	var mu sync.Mutex
+	// No token is accepted until the client has logged in again
+	valid := ""
+	client, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/auth/v1/tokens" {
+			logins.Add(1)
+			valid = "renewed"
+			json.NewEncoder(w).Encode(CleuraAuthResponse{Result: "login_ok", Token: valid})
+			return
+		}
+		if r.Header.Get("X-AUTH-TOKEN") != valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, http.StatusOK, []openstackRoleJson{{Id: "1", Name: "member"}})
+	}))
+	client.setToken("expired")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.ListRoles(context.Background(), "d")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("request failed: %s", err)
+		}
+	}
+	if n := logins.Load(); n != 1 {
+		t.Errorf("expected a single login, got %d", n)
+	}
+	if token := client.getToken(); token != "renewed" {
+		t.Errorf("expected the renewed token, got %q", token)
+	}
+}
+
+func TestDo(t *testing.T) {
+	client, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/echo":
+			var payload map[string]string
+			json.NewDecoder(r.Body).Decode(&payload)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(payload)
+		case "/empty":
+			w.WriteHeader(http.StatusOK)
+		case "/invalid":
+			w.Write([]byte("not json"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	ctx := context.Background()
+
+	var echoed map[string]string
+	if err := client.do(ctx, http.MethodPost, "echo", map[string]string{"name": "alice"}, &echoed, 201); err != nil || echoed["name"] != "alice" {
+		t.Errorf("echo: got %v, %v", echoed, err)
+	}
+	var empty map[string]string
+	if err := client.do(ctx, http.MethodGet, "empty", nil, &empty, 200); err != nil || empty != nil {
+		t.Errorf("an empty body must leave the result as it is, got %v, %v", empty, err)
+	}
+	if err := client.do(ctx, http.MethodGet, "invalid", nil, &empty, 200); err == nil {
+		t.Error("expected an error for a body that is not JSON")
+	}
+	if err := client.do(ctx, http.MethodPost, "echo", nil, nil, 200); err == nil {
+		t.Error("expected an error for an unexpected status")
+	}
+	if err := client.do(ctx, http.MethodGet, "missing", nil, &empty, 200); !IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
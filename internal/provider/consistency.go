@@ -0,0 +1,81 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	consistencyMinInterval = 500 * time.Millisecond
+	consistencyMaxInterval = 5 * time.Second
+)
+
+// consistencyError is returned by waitForConsistency when the object never matched what was applied
+// before the operation timed out. It lists the differences seen on the last read.
+type consistencyError struct {
+	Elapsed     time.Duration
+	Differences []string
+}
+
+func (e *consistencyError) Error() string {
+	return fmt.Sprintf("changes were not visible in the Cleura API after %s: %s", e.Elapsed.Round(time.Second), strings.Join(e.Differences, "; "))
+}
+
+// addConsistencyError adds the diagnostic for an error returned by waitForConsistency. operation
+// describes the change that was applied, which did succeed.
+func addConsistencyError(ctx context.Context, diags *diag.Diagnostics, operation string, err error) {
+	var consistencyErr *consistencyError
+	switch {
+	case errors.As(err, &consistencyErr):
+		diags.AddError(
+			"Inconsistent result after apply",
+			fmt.Sprintf("%s succeeded, but the %s. Increase the timeout in the timeouts block of the resource if Cleura needs more time to apply changes.", operation, err.Error()),
+		)
+	case addCancelledError(ctx, diags, operation, err):
+	default:
+		diags.AddError("Failed to read back changes", fmt.Sprintf("%s succeeded, but reading the result back failed: %s", operation, err.Error()))
+	}
+}
+
+// waitForConsistency polls read until it reports no differences between the object and what was
+// applied. Cleura propagates changes to Keystone asynchronously, so a read straight after a mutation
+// may still return the previous values. Polling stops when ctx expires, which is bounded by the
+// timeouts block of the resource.
+func waitForConsistency(ctx context.Context, read func(ctx context.Context) ([]string, error)) error {
+	start := time.Now()
+	interval := consistencyMinInterval
+	var last []string
+	for {
+		differences, err := read(ctx)
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+		if err == nil {
+			if len(differences) == 0 {
+				return nil
+			}
+			last = differences
+			tflog.Debug(ctx, fmt.Sprintf("Waiting for changes to become visible: %s", strings.Join(differences, "; ")))
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) && len(last) > 0 {
+				return &consistencyError{Elapsed: time.Since(start), Differences: last}
+			}
+			return ctx.Err()
+		case <-timer.C:
+		}
+		interval *= 2
+		if interval > consistencyMaxInterval {
+			interval = consistencyMaxInterval
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+func TestWaitForConsistencyConverges(t *testing.T) {
+	plan := ccpUserWithProjects("project", "p1", "p2")
+	observed := []ccpUserResourceModel{ccpUserWithProjects("project", "p1"), ccpUserWithProjects("project", "p2", "p1")}
+	reads := 0
+	err := waitForConsistency(context.Background(), func(ctx context.Context) ([]string, error) {
+		reads++
+		return ccpUserDifferences(plan, observed[reads-1]), nil
+	})
+	if err != nil {
+		t.Fatalf("wait: %s", err)
+	}
+	if reads != 2 {
+		t.Errorf("read %d times, expected 2", reads)
+	}
+}
+
+func TestWaitForConsistencyReadError(t *testing.T) {
+	failure := errors.New("failure")
+	err := waitForConsistency(context.Background(), func(ctx context.Context) ([]string, error) {
+		return nil, failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("expected the read error, got %v", err)
+	}
+}
+
+// TestWaitForConsistencyDeadline checks that reaching the deadline reports the differences of
+// the last read, and that the diagnostic says the operation itself succeeded.
+func TestWaitForConsistencyDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	plan := ccpUserWithProjects("project", "p1")
+	err := waitForConsistency(ctx, func(ctx context.Context) ([]string, error) {
+		return ccpUserDifferences(plan, ccpUserWithProjects("full")), nil
+	})
+	var consistencyErr *consistencyError
+	if !errors.As(err, &consistencyErr) {
+		t.Fatalf("expected a consistency error, got %v", err)
+	}
+	want := `changes were not visible in the Cleura API after 0s: openstack privilege is "full", expected "project"; project privileges are [], expected [{p1 d full}]`
+	if err.Error() != want {
+		t.Errorf("got error %q, want %q", err.Error(), want)
+	}
+
+	var diags diag.Diagnostics
+	addConsistencyError(ctx, &diags, "Creating CCP user alice", err)
+	if len(diags) != 1 || diags[0].Summary() != "Inconsistent result after apply" ||
+		!strings.HasPrefix(diags[0].Detail(), "Creating CCP user alice succeeded, but the changes were not visible") {
+		t.Errorf("unexpected diagnostics %v", diags)
+	}
+}
@@ -0,0 +1,206 @@
+package provider
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// credentialsProfileKeys are the settings a profile in the credentials file may contain.
+var credentialsProfileKeys = []string{"username", "password", "token", "totp_secret", "api_url", "region", "domain_id"}
+
+// credentialsProfile is a named section of the credentials file.
+type credentialsProfile struct {
+	Name   string
+	Path   string
+	Values map[string]string
+}
+
+// credentialsFilePath returns the location of the credentials file, $XDG_CONFIG_HOME/cleura/credentials
+// or ~/.config/cleura/credentials.
+func credentialsFilePath() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "cleura", "credentials"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "cleura", "credentials"), nil
+}
+
+// loadCredentialsProfile reads a profile from the credentials file. When name is empty the default
+// profile is used if there is one, and nil is returned if there is not. A profile that is asked for
+// by name must exist.
+func loadCredentialsProfile(name string) (*credentialsProfile, error) {
+	path, err := credentialsFilePath()
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := parseCredentialsFile(path)
+	if errors.Is(err, fs.ErrNotExist) && name == "" {
+		return nil, nil
+	}
+	if err != nil && name != "" {
+		return nil, fmt.Errorf("profile %q can not be read: %w", name, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		if _, ok := profiles["default"]; !ok {
+			return nil, nil
+		}
+		name = "default"
+	}
+	values, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %q does not exist in %s", name, path)
+	}
+	return &credentialsProfile{Name: name, Path: path, Values: values}, nil
+}
+
+// parseCredentialsFile parses an INI style file with one [section] per profile and key = value lines.
+// Lines starting with # or ; are comments.
+func parseCredentialsFile(path string) (map[string]map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	profiles := map[string]map[string]string{}
+	var current map[string]string
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if profiles[name] == nil {
+				profiles[name] = map[string]string{}
+			}
+			current = profiles[name]
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found || current == nil {
+			return nil, fmt.Errorf("%s:%d: expected a [profile] header or key = value", path, lineNumber)
+		}
+		if !slices.Contains(credentialsProfileKeys, key) {
+			return nil, fmt.Errorf("%s:%d: unknown key %q, valid keys are %s", path, lineNumber, key, strings.Join(credentialsProfileKeys, ", "))
+		}
+		current[key] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+// providerSettings resolves the provider settings by precedence: the provider configuration, then
+// the environment variable, then the credentials profile. It remembers where each value came from
+// so diagnostics can name the source.
+type providerSettings struct {
+	profile *credentialsProfile
+	sources map[string]string
+}
+
+func newProviderSettings(profile *credentialsProfile) *providerSettings {
+	return &providerSettings{profile: profile, sources: map[string]string{}}
+}
+
+// resolve returns the value of the setting named key, an empty string when it is not set anywhere.
+func (s *providerSettings) resolve(key string, config types.String, env string) string {
+	if !config.IsNull() {
+		s.sources[key] = "the provider configuration"
+		return config.ValueString()
+	}
+	if value := os.Getenv(env); value != "" {
+		s.sources[key] = "the " + env + " environment variable"
+		return value
+	}
+	if s.profile != nil && s.profile.Values[key] != "" {
+		s.sources[key] = s.profileDescription()
+		return s.profile.Values[key]
+	}
+	return ""
+}
+
+// credentialSource is one place the username, password and token can be set.
+type credentialSource struct {
+	value       func(key string) string
+	description func(key string) string
+}
+
+// resolveCredentials returns the password and token as a group, from the highest-precedence source that
+// sets either. A password in the provider configuration is therefore never combined with a token from
+// the profile. The username comes from the same source, unless it is set in the provider configuration,
+// which always wins. A username that source does not set is resolved on its own.
+func (s *providerSettings) resolveCredentials(username, password, token types.String) (string, string, string) {
+	config := map[string]types.String{"username": username, "password": password, "token": token}
+	env := map[string]string{"username": "CLEURA_USER", "password": "CLEURA_PW", "token": "CLEURA_TOKEN"}
+	sources := []credentialSource{
+		{
+			value:       func(key string) string { return config[key].ValueString() },
+			description: func(string) string { return "the provider configuration" },
+		},
+		{
+			value:       func(key string) string { return os.Getenv(env[key]) },
+			description: func(key string) string { return "the " + env[key] + " environment variable" },
+		},
+	}
+	if s.profile != nil {
+		sources = append(sources, credentialSource{
+			value:       func(key string) string { return s.profile.Values[key] },
+			description: func(string) string { return s.profileDescription() },
+		})
+	}
+	for _, source := range sources {
+		if source.value("password") == "" && source.value("token") == "" {
+			continue
+		}
+		for _, key := range []string{"password", "token"} {
+			if source.value(key) != "" {
+				s.sources[key] = source.description(key)
+			}
+		}
+		user := source.value("username")
+		if !username.IsNull() {
+			user = s.resolve("username", username, "CLEURA_USER")
+		} else if user != "" {
+			s.sources["username"] = source.description("username")
+		} else {
+			user = s.resolve("username", username, "CLEURA_USER")
+		}
+		return user, source.value("password"), source.value("token")
+	}
+	return s.resolve("username", username, "CLEURA_USER"), "", ""
+}
+
+// source describes where the setting named key was read from.
+func (s *providerSettings) source(key string) string {
+	return s.sources[key]
+}
+
+// searched describes every place a missing setting was looked for.
+func (s *providerSettings) searched(env string) string {
+	places := "the provider configuration, the " + env + " environment variable"
+	if s.profile == nil {
+		return places + " or a credentials profile"
+	}
+	return places + " or " + s.profileDescription()
+}
+
+func (s *providerSettings) profileDescription() string {
+	return fmt.Sprintf("profile %q in %s", s.profile.Name, s.profile.Path)
+}
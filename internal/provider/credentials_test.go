@@ -0,0 +1,219 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestResolveCredentials(t *testing.T) {
+	profile := &credentialsProfile{Name: "default", Path: "credentials", Values: map[string]string{"username": "profile-user", "token": "profile-token"}}
+	tests := []struct {
+		name                           string
+		config                         map[string]string
+		env                            map[string]string
+		profile                        *credentialsProfile
+		username, password, token      string
+		usernameSource, passwordSource string
+	}{
+		{
+			name:           "password in the configuration hides the profile token",
+			config:         map[string]string{"username": "config-user", "password": "config-password"},
+			profile:        profile,
+			username:       "config-user",
+			password:       "config-password",
+			usernameSource: "the provider configuration",
+			passwordSource: "the provider configuration",
+		},
+		{
+			name:           "password in the environment hides the profile token",
+			env:            map[string]string{"CLEURA_PW": "env-password"},
+			profile:        profile,
+			username:       "profile-user",
+			password:       "env-password",
+			usernameSource: `profile "default" in credentials`,
+			passwordSource: "the CLEURA_PW environment variable",
+		},
+		{
+			name:           "username in the configuration with a token from the profile",
+			config:         map[string]string{"username": "config-user"},
+			profile:        profile,
+			username:       "config-user",
+			token:          "profile-token",
+			usernameSource: "the provider configuration",
+		},
+		{
+			name:           "username in the configuration with credentials from the environment",
+			config:         map[string]string{"username": "config-user"},
+			env:            map[string]string{"CLEURA_USER": "env-user", "CLEURA_PW": "env-password"},
+			username:       "config-user",
+			password:       "env-password",
+			usernameSource: "the provider configuration",
+			passwordSource: "the CLEURA_PW environment variable",
+		},
+		{
+			name:           "the environment sets the username the configuration does not",
+			config:         map[string]string{"password": "config-password"},
+			env:            map[string]string{"CLEURA_USER": "env-user", "CLEURA_TOKEN": "env-token"},
+			username:       "env-user",
+			password:       "config-password",
+			usernameSource: "the CLEURA_USER environment variable",
+			passwordSource: "the provider configuration",
+		},
+		{
+			name:           "no password or token anywhere",
+			config:         map[string]string{"username": "config-user"},
+			username:       "config-user",
+			usernameSource: "the provider configuration",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, env := range []string{"CLEURA_USER", "CLEURA_PW", "CLEURA_TOKEN"} {
+				t.Setenv(env, tt.env[env])
+			}
+			value := func(key string) types.String {
+				if v, ok := tt.config[key]; ok {
+					return types.StringValue(v)
+				}
+				return types.StringNull()
+			}
+			settings := newProviderSettings(tt.profile)
+			username, password, token := settings.resolveCredentials(value("username"), value("password"), value("token"))
+			if username != tt.username || password != tt.password || token != tt.token {
+				t.Errorf("got %q, %q, %q, want %q, %q, %q", username, password, token, tt.username, tt.password, tt.token)
+			}
+			if got := settings.source("username"); got != tt.usernameSource {
+				t.Errorf("username source is %q, want %q", got, tt.usernameSource)
+			}
+			if got := settings.source("password"); got != tt.passwordSource {
+				t.Errorf("password source is %q, want %q", got, tt.passwordSource)
+			}
+		})
+	}
+}
+
+func writeCredentials(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cleura", "credentials")
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseCredentialsFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]map[string]string
+		wantErr string
+	}{
+		{
+			name: "comments and blank lines",
+			content: `# a comment
+; another comment
+
+[default]
+  username = alice
+password=secret = with equals
+	# indented comment
+[ ci ]
+token = abc
+`,
+			want: map[string]map[string]string{
+				"default": {"username": "alice", "password": "secret = with equals"},
+				"ci":      {"token": "abc"},
+			},
+		},
+		{
+			name:    "repeated section is merged",
+			content: "[default]\nusername = alice\n[default]\ndomain_id = d\n",
+			want:    map[string]map[string]string{"default": {"username": "alice", "domain_id": "d"}},
+		},
+		{
+			name:    "unknown key",
+			content: "[default]\nusername = alice\nuser_name = bob\n",
+			wantErr: `:3: unknown key "user_name"`,
+		},
+		{
+			name:    "key before any profile",
+			content: "username = alice\n",
+			wantErr: ":1: expected a [profile] header",
+		},
+		{
+			name:    "line without a value",
+			content: "[default]\nusername\n",
+			wantErr: ":2: expected a [profile] header or key = value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCredentialsFile(writeCredentials(t, tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected an error containing %q, got: %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse: %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadCredentialsProfile(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		profile     string
+		wantProfile string
+		wantErr     string
+	}{
+		{name: "implicit default", content: "[default]\nusername = alice\n", wantProfile: "default"},
+		{name: "no default profile", content: "[ci]\nusername = alice\n"},
+		{name: "named profile", content: "[default]\n[ci]\nusername = alice\n", profile: "ci", wantProfile: "ci"},
+		{name: "missing named profile", content: "[default]\n", profile: "ci", wantErr: `profile "ci" does not exist`},
+		{name: "no file", wantProfile: ""},
+		{name: "no file for a named profile", profile: "ci", wantErr: `profile "ci" can not be read`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.content != "" {
+				path := writeCredentials(t, tt.content)
+				t.Setenv("XDG_CONFIG_HOME", filepath.Dir(filepath.Dir(path)))
+			} else {
+				t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+			}
+			profile, err := loadCredentialsProfile(tt.profile)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected an error containing %q, got: %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load: %s", err)
+			}
+			name := ""
+			if profile != nil {
+				name = profile.Name
+			}
+			if name != tt.wantProfile {
+				t.Errorf("loaded profile %q, want %q", name, tt.wantProfile)
+			}
+		})
+	}
+}
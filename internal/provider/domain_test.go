@@ -0,0 +1,160 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+const otherDomain = "other-domain"
+
+// assertDomain fails unless every OpenStack request in requests targets domain.
+func assertDomain(t *testing.T, requests []string, domain string) {
+	t.Helper()
+	if len(requests) == 0 {
+		t.Fatal("no requests were made")
+	}
+	for _, request := range requests {
+		_, path, _ := strings.Cut(request, " ")
+		rest, ok := strings.CutPrefix(path, "/accesscontrol/v1/openstack/")
+		if !ok {
+			t.Errorf("unexpected request %s", request)
+			continue
+		}
+		if !strings.HasPrefix(rest, domain+"/") {
+			t.Errorf("request %s does not use domain %s", request, domain)
+		}
+	}
+}
+
+func TestProjectInOtherDomain(t *testing.T) {
+	api := newFakeAPI()
+	h := newResourceHarness(t, NewOpenstackProjectResource(), newFakeClient(t, api))
+
+	state, err := h.Create(openstackProjectResourceModel{
+		Id:       types.StringUnknown(),
+		Name:     types.StringValue("project"),
+		DomainId: types.StringValue(otherDomain),
+		Enabled:  types.BoolValue(true),
+	})
+	if err != nil {
+		t.Fatalf("create: %s", err)
+	}
+	var created openstackProjectResourceModel
+	h.get(state, &created)
+	if created.DomainId.ValueString() != otherDomain {
+		t.Errorf("created project has domain %s, expected %s", created.DomainId, otherDomain)
+	}
+	assertDomain(t, api.Requests(), otherDomain)
+
+	state, err = h.Read(state)
+	if err != nil {
+		t.Fatalf("read: %s", err)
+	}
+	var read openstackProjectResourceModel
+	h.get(state, &read)
+	if read.Id != created.Id || read.DomainId.ValueString() != otherDomain {
+		t.Errorf("read project %s in domain %s, expected %s in %s", read.Id, read.DomainId, created.Id, otherDomain)
+	}
+	assertDomain(t, api.Requests(), otherDomain)
+
+	state, err = h.Import(otherDomain + "/" + created.Id.ValueString())
+	if err != nil {
+		t.Fatalf("import: %s", err)
+	}
+	var imported openstackProjectResourceModel
+	h.get(state, &imported)
+	if imported.Id != created.Id || imported.DomainId.ValueString() != otherDomain || imported.Name.ValueString() != "project" {
+		t.Errorf("imported %+v, expected project %s in %s", imported, created.Id, otherDomain)
+	}
+	assertDomain(t, api.Requests(), otherDomain)
+
+	if err := h.Delete(state); err != nil {
+		t.Fatalf("delete: %s", err)
+	}
+	assertDomain(t, api.Requests(), otherDomain)
+	if len(api.projects) != 0 {
+		t.Errorf("project was not deleted: %v", api.projects)
+	}
+}
+
+func TestProjectInProviderDomain(t *testing.T) {
+	api := newFakeAPI()
+	h := newResourceHarness(t, NewOpenstackProjectResource(), newFakeClient(t, api))
+
+	state, err := h.Create(openstackProjectResourceModel{
+		Id:       types.StringUnknown(),
+		Name:     types.StringValue("project"),
+		DomainId: types.StringUnknown(),
+		Enabled:  types.BoolValue(true),
+	})
+	if err != nil {
+		t.Fatalf("create: %s", err)
+	}
+	var created openstackProjectResourceModel
+	h.get(state, &created)
+	if created.DomainId.ValueString() != "provider-domain" {
+		t.Errorf("created project has domain %s, expected the provider domain", created.DomainId)
+	}
+	if _, err := h.Import(created.Id.ValueString()); err != nil {
+		t.Fatalf("import: %s", err)
+	}
+	assertDomain(t, api.Requests(), "provider-domain")
+}
+
+func TestUserInOtherDomain(t *testing.T) {
+	api := newFakeAPI()
+	h := newResourceHarness(t, NewOpenstackUserResource(), newFakeClient(t, api))
+
+	state, err := h.Create(openstackUserResourceModel{
+		Id:               types.StringUnknown(),
+		Name:             types.StringValue("user"),
+		DomainId:         types.StringValue(otherDomain),
+		DefaultProjectId: types.StringNull(),
+		Enabled:          types.BoolValue(true),
+		Description:      types.StringNull(),
+		Projects:         []openstackUserCreateProject{{Id: "project-1", Roles: []string{"member"}}},
+		Password:         types.StringValue("Secret-Password-1"),
+		PasswordKeepers:  types.MapNull(types.StringType),
+		Timeouts:         nullTimeouts(),
+	})
+	if err != nil {
+		t.Fatalf("create: %s", err)
+	}
+	var created openstackUserResourceModel
+	h.get(state, &created)
+	assertDomain(t, api.Requests(), otherDomain)
+
+	state, err = h.Read(state)
+	if err != nil {
+		t.Fatalf("read: %s", err)
+	}
+	var read openstackUserResourceModel
+	h.get(state, &read)
+	if read.Id != created.Id || read.DomainId.ValueString() != otherDomain || len(read.Projects) != 1 {
+		t.Errorf("read %+v, expected user %s in %s with one project", read, created.Id, otherDomain)
+	}
+	assertDomain(t, api.Requests(), otherDomain)
+
+	for _, id := range []string{otherDomain + "/" + created.Id.ValueString(), otherDomain + "/name:user"} {
+		state, err = h.Import(id)
+		if err != nil {
+			t.Fatalf("import %s: %s", id, err)
+		}
+		var imported openstackUserResourceModel
+		h.get(state, &imported)
+		if imported.Id != created.Id || imported.DomainId.ValueString() != otherDomain {
+			t.Errorf("import %s: got user %s in %s, expected %s in %s", id, imported.Id, imported.DomainId, created.Id, otherDomain)
+		}
+		assertDomain(t, api.Requests(), otherDomain)
+	}
+
+	if err := h.Delete(state); err != nil {
+		t.Fatalf("delete: %s", err)
+	}
+	assertDomain(t, api.Requests(), otherDomain)
+	if len(api.users) != 0 {
+		t.Errorf("user was not deleted: %v", api.users)
+	}
+}
@@ -0,0 +1,90 @@
+package provider
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+const defaultApiUrl = "https://rest.cleura.cloud"
+
+// regionEndpoints maps the Cleura regions to the REST API serving them. The public cloud regions
+// share one endpoint, the compliant cloud has its own.
+var regionEndpoints = map[string]string{
+	"sto1":    "https://rest.cleura.cloud",
+	"sto2":    "https://rest.cleura.cloud",
+	"kna1":    "https://rest.cleura.cloud",
+	"fra1":    "https://rest.cleura.cloud",
+	"sto-com": "https://rest.compliant.cleura.cloud",
+}
+
+// endpointForRegion returns the REST API of region, which is matched regardless of case.
+func endpointForRegion(region string) (string, error) {
+	endpoint, ok := regionEndpoints[strings.ToLower(region)]
+	if !ok {
+		regions := make([]string, 0, len(regionEndpoints))
+		for r := range regionEndpoints {
+			regions = append(regions, r)
+		}
+		sort.Strings(regions)
+		return "", fmt.Errorf("unknown region %q, valid regions are %s", region, strings.Join(regions, ", "))
+	}
+	return endpoint, nil
+}
+
+// validateApiUrl checks that apiUrl is an absolute http or https URL. Paths are joined to it with
+// a slash, so a trailing slash is rejected rather than producing double slashes.
+func validateApiUrl(apiUrl string) error {
+	parsed, err := url.Parse(apiUrl)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != "https" && parsed.Scheme != "http" {
+		return fmt.Errorf("%q must start with https:// or http://", apiUrl)
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("%q has no host", apiUrl)
+	}
+	if strings.HasSuffix(apiUrl, "/") {
+		return fmt.Errorf("%q must not end with a slash, use %q", apiUrl, strings.TrimRight(apiUrl, "/"))
+	}
+	if parsed.RawQuery != "" || parsed.Fragment != "" {
+		return fmt.Errorf("%q must not contain a query or fragment", apiUrl)
+	}
+	return nil
+}
+
+// isUnreachable reports whether err means that no response was received from the API at all,
+// because the host could not be resolved, connected to or completed the TLS handshake, or did not
+// answer in time. Other errors returned by the transport, such as a failed re-login, are not.
+func isUnreachable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	if errors.As(err, &dnsErr) || errors.As(err, &opErr) || errors.As(err, &certErr) || errors.As(err, &recordErr) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// addUnreachableError adds the diagnostic for a Cleura API that did not respond at all.
+func addUnreachableError(diags *diag.Diagnostics, apiUrl string, source string, err error) {
+	diags.AddAttributeError(
+		path.Root("api_url"),
+		"Cleura API unreachable",
+		fmt.Sprintf("Could not connect to %s, the url from %s. Check api_url and region, and that the network allows connections to it. Error: %s", apiUrl, source, err.Error()),
+	)
+}
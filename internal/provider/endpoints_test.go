@@ -0,0 +1,109 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestIsUnreachable(t *testing.T) {
+	t.Run("rejected token", func(t *testing.T) {
+		client, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		client.useToken("expired")
+		err := client.ValidateToken(context.Background())
+		if err == nil {
+			t.Fatal("expected the token to be rejected")
+		}
+		if isUnreachable(err) {
+			t.Errorf("rejected token reported as unreachable: %s", err)
+		}
+	})
+	t.Run("failed relogin", func(t *testing.T) {
+		err := &url.Error{Op: "Get", URL: "https://rest.cleura.cloud", Err: fmt.Errorf("token expired and login failed: %w", errors.New("401 Unauthorized"))}
+		if isUnreachable(err) {
+			t.Errorf("failed relogin reported as unreachable: %s", err)
+		}
+	})
+	t.Run("connection refused", func(t *testing.T) {
+		_, server := newTestClient(t, http.NotFoundHandler())
+		server.Close()
+		client := NewCleuraClient("user", "password", server.URL, "provider-domain", 5*time.Second, retryPolicy{})
+		err := client.Login(context.Background())
+		if !isUnreachable(err) {
+			t.Errorf("connection refused not reported as unreachable: %v", err)
+		}
+	})
+	t.Run("unknown host", func(t *testing.T) {
+		client := NewCleuraClient("user", "password", "http://cleura.invalid", "provider-domain", 5*time.Second, retryPolicy{})
+		err := client.Login(context.Background())
+		if !isUnreachable(err) {
+			t.Errorf("unknown host not reported as unreachable: %v", err)
+		}
+	})
+	t.Run("cancelled", func(t *testing.T) {
+		if isUnreachable(&url.Error{Op: "Get", URL: "https://rest.cleura.cloud", Err: context.Canceled}) {
+			t.Error("cancelled request reported as unreachable")
+		}
+	})
+}
+
+func TestValidateApiUrl(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://rest.cleura.cloud", false},
+		{"http://localhost:8080", false},
+		{"https://cleura.example.com/api", false},
+		{"https://rest.cleura.cloud/", true},
+		{"https://rest.cleura.cloud/api/", true},
+		{"rest.cleura.cloud", true},
+		{"ftp://rest.cleura.cloud", true},
+		{"https://", true},
+		{"https:///api", true},
+		{"https://rest.cleura.cloud?region=sto1", true},
+		{"https://rest.cleura.cloud#top", true},
+		{"https://rest cleura.cloud", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := validateApiUrl(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateApiUrl(%q) = %v, want error %t", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEndpointForRegion(t *testing.T) {
+	tests := []struct {
+		region  string
+		want    string
+		wantErr bool
+	}{
+		{"sto2", "https://rest.cleura.cloud", false},
+		{"FRA1", "https://rest.cleura.cloud", false},
+		{"sto-com", "https://rest.compliant.cleura.cloud", false},
+		{"sto3", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.region, func(t *testing.T) {
+			got, err := endpointForRegion(tt.region)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("endpointForRegion(%q) = %q, %v, want %q", tt.region, got, err, tt.want)
+			}
+		})
+	}
+	for region, endpoint := range regionEndpoints {
+		if err := validateApiUrl(endpoint); err != nil {
+			t.Errorf("endpoint of region %s is not valid: %s", region, err)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeAPI is an in-memory Cleura API with the OpenStack project and user endpoints and the CCP
+// user endpoints. Every request is recorded as "METHOD path".
+type fakeAPI struct {
+	mu       sync.Mutex
+	requests []string
+	nextId   int
+	// projects, users and passwords are keyed by domain and id, memberships by domain, user and project
+	projects    map[string]openstackProjectResourceJson
+	users       map[string]openstackUserDatasourceModelJson
+	memberships map[string]map[string][]string
+	passwords   map[string]string
+	ccpUsers    map[string]ccpUserResourceModelJson
+	ccpIds      map[string]string
+	// fail makes the request with the given "METHOD path" answer with the status code
+	fail map[string]int
+}
+
+func newFakeAPI() *fakeAPI {
+	return &fakeAPI{
+		projects:    map[string]openstackProjectResourceJson{},
+		users:       map[string]openstackUserDatasourceModelJson{},
+		memberships: map[string]map[string][]string{},
+		passwords:   map[string]string{},
+		ccpUsers:    map[string]ccpUserResourceModelJson{},
+		ccpIds:      map[string]string{},
+		fail:        map[string]int{},
+	}
+}
+
+// newFakeClient serves api and returns a client for it whose provider domain is provider-domain.
+func newFakeClient(t *testing.T, api *fakeAPI) *CleuraClient {
+	t.Helper()
+	client, _ := newTestClient(t, api.handler())
+	return client
+}
+
+// Requests returns the recorded requests and forgets them.
+func (f *fakeAPI) Requests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	requests := f.requests
+	f.requests = nil
+	return requests
+}
+
+func (f *fakeAPI) id(prefix string) string {
+	f.nextId++
+	return fmt.Sprintf("%s-%d", prefix, f.nextId)
+}
+
+func (f *fakeAPI) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /accesscontrol/v1/openstack/{domain}/projects", f.createProject)
+	mux.HandleFunc("GET /accesscontrol/v1/openstack/{domain}/projects", f.listProjects)
+	mux.HandleFunc("GET /accesscontrol/v1/openstack/{domain}/projects/{id}", f.getProject)
+	mux.HandleFunc("PUT /accesscontrol/v1/openstack/{domain}/projects/{id}", f.updateProject)
+	mux.HandleFunc("DELETE /accesscontrol/v1/openstack/{domain}/projects/{id}", f.deleteProject)
+	mux.HandleFunc("GET /accesscontrol/v1/openstack/{domain}/roles", f.listRoles)
+	mux.HandleFunc("POST /accesscontrol/v1/openstack/{domain}/users", f.createUser)
+	mux.HandleFunc("GET /accesscontrol/v1/openstack/{domain}/users", f.listUsers)
+	mux.HandleFunc("GET /accesscontrol/v1/openstack/{domain}/users/{id}", f.getUser)
+	mux.HandleFunc("PUT /accesscontrol/v1/openstack/{domain}/users/{id}", f.updateUser)
+	mux.HandleFunc("DELETE /accesscontrol/v1/openstack/{domain}/users/{id}", f.deleteUser)
+	mux.HandleFunc("POST /accesscontrol/v1/openstack/{domain}/users/{id}/projects", f.addRoles)
+	mux.HandleFunc("DELETE /accesscontrol/v1/openstack/{domain}/users/{id}/projects/{project}/{role}", f.removeRole)
+	mux.HandleFunc("POST /accesscontrol/v1/users", f.createCCPUser)
+	mux.HandleFunc("GET /accesscontrol/v1/users", f.listCCPUsers)
+	mux.HandleFunc("GET /accesscontrol/v1/users/{name}", f.getCCPUser)
+	mux.HandleFunc("DELETE /accesscontrol/v1/users/{name}", f.deleteCCPUser)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		request := r.Method + " " + r.URL.Path
+		f.requests = append(f.requests, request)
+		if status, ok := f.fail[request]; ok {
+			writeJSON(w, status, apiError{Error: apiErrorDetails{Code: status, Message: http.StatusText(status)}})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func notFound(w http.ResponseWriter) {
+	writeJSON(w, http.StatusNotFound, apiError{Error: apiErrorDetails{Code: 404, Message: "Not Found"}})
+}
+
+func (f *fakeAPI) createProject(w http.ResponseWriter, r *http.Request) {
+	var req openstackProjectRequestJson
+	json.NewDecoder(r.Body).Decode(&req)
+	project := req.Project
+	project.Id = f.id("project")
+	project.DomainId = r.PathValue("domain")
+	f.projects[project.DomainId+"/"+project.Id] = project
+	writeJSON(w, http.StatusCreated, project)
+}
+
+func (f *fakeAPI) listProjects(w http.ResponseWriter, r *http.Request) {
+	projects := []openstackProjectResourceJson{}
+	for _, p := range f.projects {
+		if p.DomainId == r.PathValue("domain") {
+			projects = append(projects, p)
+		}
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].Id < projects[j].Id })
+	writeJSON(w, http.StatusOK, projects)
+}
+
+func (f *fakeAPI) getProject(w http.ResponseWriter, r *http.Request) {
+	project, ok := f.projects[r.PathValue("domain")+"/"+r.PathValue("id")]
+	if !ok {
+		notFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, project)
+}
+
+func (f *fakeAPI) updateProject(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("domain") + "/" + r.PathValue("id")
+	project, ok := f.projects[key]
+	if !ok {
+		notFound(w)
+		return
+	}
+	var req openstackProjectRequestJson
+	json.NewDecoder(r.Body).Decode(&req)
+	req.Project.Id, req.Project.DomainId = project.Id, project.DomainId
+	f.projects[key] = req.Project
+	writeJSON(w, http.StatusOK, req.Project)
+}
+
+func (f *fakeAPI) deleteProject(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("domain") + "/" + r.PathValue("id")
+	if _, ok := f.projects[key]; !ok {
+		notFound(w)
+		return
+	}
+	delete(f.projects, key)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeAPI) listRoles(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, []openstackRoleJson{{Id: "role-1", Name: "member"}, {Id: "role-2", Name: "reader"}})
+}
+
+// user returns the user with its current memberships.
+func (f *fakeAPI) user(key string) (openstackUserDatasourceModelJson, bool) {
+	user, ok := f.users[key]
+	if !ok {
+		return user, false
+	}
+	user.Projects = nil
+	for _, projectId := range sortedKeys(f.memberships[key]) {
+		project := openstackProjectJson{Id: projectId, DomainId: user.DomainId}
+		for _, role := range f.memberships[key][projectId] {
+			project.Roles = append(project.Roles, openstackRoleJson{Id: role, Name: role})
+		}
+		user.Projects = append(user.Projects, project)
+	}
+	return user, true
+}
+
+func (f *fakeAPI) createUser(w http.ResponseWriter, r *http.Request) {
+	var req createOpenstackUser
+	json.NewDecoder(r.Body).Decode(&req)
+	domain := r.PathValue("domain")
+	for _, u := range f.users {
+		if u.DomainId == domain && u.Name == req.User.Name {
+			writeJSON(w, http.StatusConflict, apiError{Error: apiErrorDetails{Code: 409, Message: "Conflict"}})
+			return
+		}
+	}
+	user := openstackUserDatasourceModelJson{Id: f.id("user"), Name: req.User.Name, DomainId: domain, Enabled: true, Description: req.User.Description}
+	key := domain + "/" + user.Id
+	f.users[key] = user
+	f.passwords[key] = req.User.Password
+	f.memberships[key] = map[string][]string{}
+	for _, p := range req.Projects {
+		f.memberships[key][p.Id] = append([]string{}, p.Roles...)
+	}
+	writeJSON(w, http.StatusCreated, openstackUserCreatedModel{Id: user.Id, Name: user.Name, DomainId: domain, Enabled: true})
+}
+
+func (f *fakeAPI) listUsers(w http.ResponseWriter, r *http.Request) {
+	users := []openstackUserDatasourceModelJson{}
+	for _, key := range sortedKeys(f.users) {
+		if strings.HasPrefix(key, r.PathValue("domain")+"/") {
+			user, _ := f.user(key)
+			users = append(users, user)
+		}
+	}
+	writeJSON(w, http.StatusOK, users)
+}
+
+func (f *fakeAPI) getUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := f.user(r.PathValue("domain") + "/" + r.PathValue("id"))
+	if !ok {
+		notFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
+
+func (f *fakeAPI) updateUser(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("domain") + "/" + r.PathValue("id")
+	user, ok := f.users[key]
+	if !ok {
+		notFound(w)
+		return
+	}
+	var req openstackUserUpdate
+	json.NewDecoder(r.Body).Decode(&req)
+	if req.User.Enabled != nil {
+		user.Enabled = *req.User.Enabled
+	}
+	if req.User.Description != nil {
+		user.Description = *req.User.Description
+	}
+	if req.User.Password != "" {
+		f.passwords[key] = req.User.Password
+	}
+	f.users[key] = user
+	writeJSON(w, http.StatusOK, user)
+}
+
+func (f *fakeAPI) deleteUser(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("domain") + "/" + r.PathValue("id")
+	if _, ok := f.users[key]; !ok {
+		notFound(w)
+		return
+	}
+	delete(f.users, key)
+	delete(f.memberships, key)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeAPI) addRoles(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("domain") + "/" + r.PathValue("id")
+	if _, ok := f.users[key]; !ok {
+		notFound(w)
+		return
+	}
+	var req openstackProjectUpdate
+	json.NewDecoder(r.Body).Decode(&req)
+	for _, p := range req.Projects {
+		for _, role := range p.Roles {
+			roles := f.memberships[key][p.ProjectId]
+			if !containsString(roles, role) {
+				f.memberships[key][p.ProjectId] = append(roles, role)
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+func (f *fakeAPI) removeRole(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("domain") + "/" + r.PathValue("id")
+	if _, ok := f.users[key]; !ok {
+		notFound(w)
+		return
+	}
+	project, role := r.PathValue("project"), r.PathValue("role")
+	var remaining []string
+	for _, existing := range f.memberships[key][project] {
+		if existing != role {
+			remaining = append(remaining, existing)
+		}
+	}
+	if len(remaining) == 0 {
+		delete(f.memberships[key], project)
+	} else {
+		f.memberships[key][project] = remaining
+	}
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeAPI) createCCPUser(w http.ResponseWriter, r *http.Request) {
+	var req ccpUserCreateJson
+	json.NewDecoder(r.Body).Decode(&req)
+	f.nextId++
+	req.User.Password = ""
+	f.ccpUsers[req.User.Name] = req.User
+	f.ccpIds[req.User.Name] = fmt.Sprint(f.nextId)
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+// ccpUser is the representation of a CCP user returned by the API, which adds the numeric id.
+type ccpUser struct {
+	Id string `json:"id"`
+	ccpUserResourceModelJson
+}
+
+func (f *fakeAPI) listCCPUsers(w http.ResponseWriter, r *http.Request) {
+	users := []ccpUser{}
+	for _, name := range sortedKeys(f.ccpUsers) {
+		users = append(users, ccpUser{Id: f.ccpIds[name], ccpUserResourceModelJson: f.ccpUsers[name]})
+	}
+	writeJSON(w, http.StatusOK, users)
+}
+
+func (f *fakeAPI) getCCPUser(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	user, ok := f.ccpUsers[name]
+	if !ok {
+		notFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, ccpUser{Id: f.ccpIds[name], ccpUserResourceModelJson: user})
+}
+
+func (f *fakeAPI) deleteCCPUser(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if _, ok := f.ccpUsers[name]; !ok {
+		notFound(w)
+		return
+	}
+	delete(f.ccpUsers, name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// seedUser adds an enabled user with the given project roles.
+func (f *fakeAPI) seedUser(domain, id, name string, projects map[string][]string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := domain + "/" + id
+	f.users[key] = openstackUserDatasourceModelJson{Id: id, Name: name, DomainId: domain, Enabled: true}
+	f.memberships[key] = map[string][]string{}
+	for project, roles := range projects {
+		f.memberships[key][project] = append([]string{}, roles...)
+	}
+}
+
+// roles returns the project roles of a user, sorted by project and role.
+func (f *fakeAPI) roles(domain, id string) []openstackUserCreateProject {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m := projectMembership{}
+	for project, roles := range f.memberships[domain+"/"+id] {
+		m[project] = map[string]bool{}
+		for _, r := range roles {
+			m[project][r] = true
+		}
+	}
+	return m.projects()
+}
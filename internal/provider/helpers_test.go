@@ -0,0 +1,42 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client that talks to a fake Cleura API served by handler. Retries are
+// disabled so every request reaches handler exactly once.
+func newTestClient(t *testing.T, handler http.Handler) (*CleuraClient, *httptest.Server) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client := NewCleuraClient("user", "password", server.URL, "provider-domain", 5*time.Second, retryPolicy{})
+	client.setToken("token")
+	return client, server
+}
+
+// cancelAfter cancels an operation once the responses of a number of requests have been received.
+type cancelAfter struct {
+	base      http.RoundTripper
+	cancel    context.CancelFunc
+	remaining atomic.Int32
+}
+
+func cancelAfterResponses(base http.RoundTripper, cancel context.CancelFunc, responses int32) *cancelAfter {
+	c := &cancelAfter{base: base, cancel: cancel}
+	c.remaining.Store(responses)
+	return c
+}
+
+func (c *cancelAfter) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := c.base.RoundTrip(req)
+	if c.remaining.Add(-1) == 0 {
+		c.cancel()
+	}
+	return resp, err
+}
@@ -0,0 +1,133 @@
+package provider
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// verifiedUser drops the attributes that can not be imported, the password is never returned by the API.
+func verifiedUser(user openstackUserResourceModel) openstackUserResourceModel {
+	user.Password = types.StringNull()
+	user.PasswordKeepers = types.MapNull(types.StringType)
+	user.Timeouts = nullTimeouts()
+	return user
+}
+
+func TestImportOpenstackUser(t *testing.T) {
+	api := newFakeAPI()
+	h := newResourceHarness(t, NewOpenstackUserResource(), newFakeClient(t, api))
+	state, err := h.Create(openstackUserResourceModel{
+		Id:               types.StringUnknown(),
+		Name:             types.StringValue("alice"),
+		DomainId:         types.StringValue("d"),
+		DefaultProjectId: types.StringNull(),
+		Enabled:          types.BoolValue(true),
+		Description:      types.StringValue("Alice"),
+		Projects:         []openstackUserCreateProject{{Id: "p1", Roles: []string{"member", "reader"}}},
+		Password:         types.StringValue("Secret-Password-1"),
+		PasswordKeepers:  types.MapNull(types.StringType),
+		Timeouts:         nullTimeouts(),
+	})
+	if err != nil {
+		t.Fatalf("create: %s", err)
+	}
+	var created openstackUserResourceModel
+	h.get(state, &created)
+
+	for _, id := range []string{"d/" + created.Id.ValueString(), "d/name:alice"} {
+		t.Run(id, func(t *testing.T) {
+			state, err := h.Import(id)
+			if err != nil {
+				t.Fatalf("import: %s", err)
+			}
+			var imported openstackUserResourceModel
+			h.get(state, &imported)
+			if want := verifiedUser(created); !reflect.DeepEqual(verifiedUser(imported), want) {
+				t.Errorf("imported %+v, want %+v", verifiedUser(imported), want)
+			}
+		})
+	}
+}
+
+func TestImportOpenstackUserInvalid(t *testing.T) {
+	api := newFakeAPI()
+	api.seedUser("d", "u1", "alice", nil)
+	h := newResourceHarness(t, NewOpenstackUserResource(), newFakeClient(t, api))
+	tests := map[string]string{
+		"/u1":          "Invalid import ID",
+		"d/":           "Invalid import ID",
+		"d/name:bob":   "User not found",
+		"e/name:alice": "User not found",
+	}
+	for id, summary := range tests {
+		t.Run(id, func(t *testing.T) {
+			_, err := h.Import(id)
+			if err == nil || !strings.Contains(err.Error(), summary) {
+				t.Errorf("expected %q, got: %v", summary, err)
+			}
+		})
+	}
+}
+
+func TestImportCCPUser(t *testing.T) {
+	api := newFakeAPI()
+	h := newResourceHarness(t, NewCCPUserResource(), newFakeClient(t, api))
+	state, err := h.Create(ccpUserResourceModel{
+		Id:        types.StringUnknown(),
+		Name:      types.StringValue("alice"),
+		Email:     types.StringValue("alice@example.com"),
+		FirstName: types.StringValue("Alice"),
+		LastName:  types.StringNull(),
+		Privileges: &ccpResourcePrivileges{
+			Users: &ccpUserResourceUserPrivilege{Type: types.StringValue("full")},
+		},
+		Timeouts: nullTimeouts(),
+	})
+	if err != nil {
+		t.Fatalf("create: %s", err)
+	}
+	var created ccpUserResourceModel
+	h.get(state, &created)
+	if created.Id.ValueString() == "" {
+		t.Fatal("created user has no id")
+	}
+
+	for _, id := range []string{"alice", created.Id.ValueString()} {
+		t.Run(id, func(t *testing.T) {
+			state, err := h.Import(id)
+			if err != nil {
+				t.Fatalf("import: %s", err)
+			}
+			var imported ccpUserResourceModel
+			h.get(state, &imported)
+			imported.Timeouts = created.Timeouts
+			if !reflect.DeepEqual(imported, created) {
+				t.Errorf("imported %+v, want %+v", imported, created)
+			}
+		})
+	}
+}
+
+func TestImportCCPUserNumericName(t *testing.T) {
+	api := newFakeAPI()
+	// A login name of digits only that does not match any id is imported as a name
+	api.ccpUsers["4711"] = ccpUserResourceModelJson{Name: "4711", Email: "4711@example.com"}
+	api.ccpIds["4711"] = "1"
+	h := newResourceHarness(t, NewCCPUserResource(), newFakeClient(t, api))
+	state, err := h.Import("4711")
+	if err != nil {
+		t.Fatalf("import: %s", err)
+	}
+	var imported ccpUserResourceModel
+	h.get(state, &imported)
+	if imported.Name.ValueString() != "4711" || imported.Id.ValueString() != "1" {
+		t.Errorf("imported %s with id %s, want 4711 with id 1", imported.Name, imported.Id)
+	}
+
+	if _, err := h.Import("bob"); err == nil || !strings.Contains(err.Error(), "Failed to import CCP user") {
+		t.Errorf("expected an error for an unknown user, got: %v", err)
+	}
+}
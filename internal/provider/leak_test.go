@@ -0,0 +1,139 @@
+package provider
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// newCountingClient serves handler and counts the connections the client opens. A response body
+// that is not drained and closed keeps its connection busy, so the next request opens a new one.
+func newCountingClient(t *testing.T, handler http.Handler, retry retryPolicy) (*CleuraClient, *atomic.Int32) {
+	t.Helper()
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(handler)
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+	client := NewCleuraClient("user", "password", server.URL, "d", 5*time.Second, retry)
+	client.setToken("token")
+	return client, &conns
+}
+
+// padding makes response bodies larger than what the transport discards by itself when a body is
+// closed early, but smaller than what closeBody drains.
+var padding = strings.Repeat(" ", 512<<10)
+
+func TestBodiesAreDrained(t *testing.T) {
+	api := newFakeAPI()
+	api.seedUser("d", "u1", "alice", map[string][]string{"p1": {"member"}})
+	handler := api.handler()
+	padded := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+		w.Write([]byte(padding))
+	})
+	tests := map[string]func(c *CleuraClient) error{
+		"success": func(c *CleuraClient) error {
+			_, err := c.GetUserResource(context.Background(), "d", "u1")
+			return err
+		},
+		"not found": func(c *CleuraClient) error {
+			_, err := c.GetUserResource(context.Background(), "d", "missing")
+			if !IsNotFound(err) {
+				return err
+			}
+			return nil
+		},
+		"no content": func(c *CleuraClient) error {
+			return c.ToggleUserEnabled(context.Background(), "d", "u1", true)
+		},
+		"list": func(c *CleuraClient) error {
+			_, _, err := c.FindUserByName(context.Background(), "d", "alice")
+			return err
+		},
+	}
+	for name, request := range tests {
+		t.Run(name, func(t *testing.T) {
+			client, conns := newCountingClient(t, padded, retryPolicy{})
+			for i := 0; i < 20; i++ {
+				if err := request(client); err != nil {
+					t.Fatalf("request %d: %s", i, err)
+				}
+			}
+			if n := conns.Load(); n != 1 {
+				t.Errorf("20 requests used %d connections, expected 1", n)
+			}
+		})
+	}
+}
+
+func TestBodiesAreDrainedOnRetry(t *testing.T) {
+	var attempts atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Every other attempt is rejected as rate limited, the rejected body must be drained before the retry
+		if attempts.Add(1)%2 == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(padding))
+			return
+		}
+		writeJSON(w, http.StatusOK, []openstackRoleJson{})
+	})
+	client, conns := newCountingClient(t, handler, retryPolicy{MaxRetries: 1})
+	for i := 0; i < 10; i++ {
+		if _, err := client.ListRoles(context.Background(), "d"); err != nil {
+			t.Fatalf("request %d: %s", i, err)
+		}
+	}
+	if n := attempts.Load(); n != 20 {
+		t.Errorf("expected every request to be retried once, got %d attempts", n)
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("20 attempts used %d connections, expected 1", n)
+	}
+}
+
+func TestReadDrainsBodies(t *testing.T) {
+	api := newFakeAPI()
+	api.seedUser("d", "u1", "alice", map[string][]string{"p1": {"member"}})
+	handler := api.handler()
+	client, conns := newCountingClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+		w.Write([]byte(padding))
+	}), retryPolicy{})
+	h := newResourceHarness(t, NewOpenstackUserResource(), client)
+	state := h.state(openstackUserResourceModel{
+		Id:               types.StringValue("u1"),
+		Name:             types.StringValue("alice"),
+		DomainId:         types.StringValue("d"),
+		DefaultProjectId: types.StringNull(),
+		Enabled:          types.BoolValue(true),
+		Description:      types.StringNull(),
+		Projects:         []openstackUserCreateProject{{Id: "p1", Roles: []string{"member"}}},
+		Password:         types.StringNull(),
+		PasswordKeepers:  types.MapNull(types.StringType),
+		Timeouts:         nullTimeouts(),
+	})
+	for i := 0; i < 10; i++ {
+		if _, err := h.Read(state); err != nil {
+			t.Fatalf("read %d: %s", i, err)
+		}
+	}
+	if requests := api.Requests(); len(requests) != 10 {
+		t.Errorf("expected a single request per read, got %d: %v", len(requests), requests)
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("10 reads used %d connections, expected 1", n)
+	}
+}
@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
@@ -68,6 +69,9 @@ type openstackUserResourceModel struct {
 	Enabled          types.Bool                   `json:"enabled" tfsdk:"enabled"`
 	Description      types.String                 `json:"description,omitempty" tfsdk:"description"`
 	Projects         []openstackUserCreateProject `json:"projects,omitempty" tfsdk:"projects"`
+	Password         types.String                 `json:"-" tfsdk:"password"`
+	PasswordKeepers  types.Map                    `json:"-" tfsdk:"password_rotation_keepers"`
+	Timeouts         timeouts.Value               `json:"-" tfsdk:"timeouts"`
 	// Client           *CleuraClient
 }
 
@@ -111,7 +115,9 @@ type openstackUserUpdate struct {
 	User openstackUserUpdateProperties `json:"user"`
 }
 type openstackUserUpdateProperties struct {
-	Enabled bool `json:"enabled"`
+	Enabled     *bool   `json:"enabled,omitempty"`
+	Password    string  `json:"password,omitempty"`
+	Description *string `json:"description,omitempty"`
 }
 type openstackProjectUpdate struct {
 	Projects []openstackProjectAssignment `json:"projects"`
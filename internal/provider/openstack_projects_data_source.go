@@ -0,0 +1,176 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// ---------- Types
+type openstackProjectsDataSourceModel struct {
+	DomainId  types.String                   `tfsdk:"domain_id"`
+	NameRegex types.String                   `tfsdk:"name_regex"`
+	Enabled   types.Bool                     `tfsdk:"enabled"`
+	Tag       types.String                   `tfsdk:"tag"`
+	Ids       []string                       `tfsdk:"ids"`
+	Projects  []openstackProjectsDataProject `tfsdk:"projects"`
+}
+
+type openstackProjectsDataProject struct {
+	Id          types.String `tfsdk:"id"`
+	Name        types.String `tfsdk:"name"`
+	Description types.String `tfsdk:"description"`
+	Enabled     types.Bool   `tfsdk:"enabled"`
+	ParentId    types.String `tfsdk:"parent_id"`
+	Tags        []string     `tfsdk:"tags"`
+}
+
+// --------
+
+type openstackProjectsDataSource struct {
+	Client *CleuraClient
+}
+
+func NewOpenstackProjectsDataSource() datasource.DataSource {
+	return &openstackProjectsDataSource{}
+}
+
+// Configure implements datasource.DataSourceWithConfigure.
+func (c *openstackProjectsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	client, ok := req.ProviderData.(*CleuraClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unable to cast ProviderData to *CleuraClient",
+			fmt.Sprintf("Expected *CleuraClient, got: %T", req.ProviderData),
+		)
+		return
+	}
+	c.Client = client
+}
+
+// Metadata implements datasource.DataSource.
+func (c *openstackProjectsDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_openstack_projects"
+}
+
+// Read implements datasource.DataSource.
+func (c *openstackProjectsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data openstackProjectsDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	var nameRegex *regexp.Regexp
+	if !data.NameRegex.IsNull() {
+		var err error
+		nameRegex, err = regexp.Compile(data.NameRegex.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("name_regex"), "Invalid name_regex", err.Error())
+			return
+		}
+	}
+	domainId := c.Client.domainFor(data.DomainId)
+	projects, err := c.Client.ListProjects(ctx, domainId)
+	if err != nil {
+		if addCancelledError(ctx, &resp.Diagnostics, "Listing projects", err) {
+			return
+		}
+		resp.Diagnostics.AddError(
+			"Failed to list projects",
+			err.Error(),
+		)
+		return
+	}
+	data.DomainId = types.StringValue(domainId)
+	data.Ids = []string{}
+	data.Projects = []openstackProjectsDataProject{}
+	for _, p := range projects {
+		if nameRegex != nil && !nameRegex.MatchString(p.Name) {
+			continue
+		}
+		if !data.Enabled.IsNull() && data.Enabled.ValueBool() != p.Enabled {
+			continue
+		}
+		if !data.Tag.IsNull() && !slices.Contains(p.Tags, data.Tag.ValueString()) {
+			continue
+		}
+		project := getProjectModel(p)
+		data.Ids = append(data.Ids, p.Id)
+		data.Projects = append(data.Projects, openstackProjectsDataProject{
+			Id:          project.Id,
+			Name:        project.Name,
+			Description: project.Description,
+			Enabled:     project.Enabled,
+			ParentId:    project.ParentId,
+			Tags:        project.Tags,
+		})
+	}
+	diags := resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+// Schema implements datasource.DataSource.
+func (c *openstackProjectsDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Lists the OpenStack projects of a domain in Cleura Cloud",
+		Attributes: map[string]schema.Attribute{
+			"domain_id": schema.StringAttribute{
+				Description: "Domain to list the projects of. Defaults to the domain_id of the provider.",
+				Optional:    true,
+				Computed:    true,
+			},
+			"name_regex": schema.StringAttribute{
+				Description: "Only return projects whose name matches the regular expression.",
+				Optional:    true,
+			},
+			"enabled": schema.BoolAttribute{
+				Description: "Only return enabled or disabled projects.",
+				Optional:    true,
+			},
+			"tag": schema.StringAttribute{
+				Description: "Only return projects with the tag.",
+				Optional:    true,
+			},
+			"ids": schema.ListAttribute{
+				Description: "IDs of the matching projects.",
+				Computed:    true,
+				ElementType: types.StringType,
+			},
+			"projects": schema.ListNestedAttribute{
+				Computed: true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							Computed: true,
+						},
+						"name": schema.StringAttribute{
+							Computed: true,
+						},
+						"description": schema.StringAttribute{
+							Computed: true,
+						},
+						"enabled": schema.BoolAttribute{
+							Computed: true,
+						},
+						"parent_id": schema.StringAttribute{
+							Computed: true,
+						},
+						"tags": schema.SetAttribute{
+							Computed:    true,
+							ElementType: types.StringType,
+						},
+					},
+				},
+			},
+		},
+	}
+}
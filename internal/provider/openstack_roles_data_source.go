@@ -0,0 +1,152 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// ---------- Types
+type openstackRolesDataSourceModel struct {
+	DomainId  types.String    `tfsdk:"domain_id"`
+	NameRegex types.String    `tfsdk:"name_regex"`
+	Names     []string        `tfsdk:"names"`
+	Roles     []openstackRole `tfsdk:"roles"`
+}
+
+// --------
+
+type openstackRolesDataSource struct {
+	Client *CleuraClient
+}
+
+func NewOpenstackRolesDataSource() datasource.DataSource {
+	return &openstackRolesDataSource{}
+}
+
+// Configure implements datasource.DataSourceWithConfigure.
+func (c *openstackRolesDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	client, ok := req.ProviderData.(*CleuraClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unable to cast ProviderData to *CleuraClient",
+			fmt.Sprintf("Expected *CleuraClient, got: %T", req.ProviderData),
+		)
+		return
+	}
+	c.Client = client
+}
+
+// Metadata implements datasource.DataSource.
+func (c *openstackRolesDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_openstack_roles"
+}
+
+// Read implements datasource.DataSource.
+func (c *openstackRolesDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data openstackRolesDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	var nameRegex *regexp.Regexp
+	if !data.NameRegex.IsNull() {
+		var err error
+		nameRegex, err = regexp.Compile(data.NameRegex.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("name_regex"), "Invalid name_regex", err.Error())
+			return
+		}
+	}
+	domainId := c.Client.domainFor(data.DomainId)
+	roles, err := c.Client.ListRoles(ctx, domainId)
+	if err != nil {
+		if addCancelledError(ctx, &resp.Diagnostics, "Listing roles", err) {
+			return
+		}
+		resp.Diagnostics.AddError(
+			"Failed to list roles",
+			err.Error(),
+		)
+		return
+	}
+	var available []string
+	for _, r := range roles {
+		available = append(available, r.Name)
+	}
+	// Every requested name must exist, so a typo fails the plan instead of the apply
+	for _, name := range data.Names {
+		if !slices.Contains(available, name) {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("names"),
+				"Unknown role",
+				fmt.Sprintf("Role %q does not exist in domain %s. Valid roles are: %s", name, domainId, strings.Join(available, ", ")),
+			)
+		}
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.DomainId = types.StringValue(domainId)
+	data.Roles = []openstackRole{}
+	for _, r := range roles {
+		if nameRegex != nil && !nameRegex.MatchString(r.Name) {
+			continue
+		}
+		if data.Names != nil && !slices.Contains(data.Names, r.Name) {
+			continue
+		}
+		data.Roles = append(data.Roles, openstackRole{
+			Id:   types.StringValue(r.Id),
+			Name: types.StringValue(r.Name),
+		})
+	}
+	diags := resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+// Schema implements datasource.DataSource.
+func (c *openstackRolesDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Lists the OpenStack roles available in a domain in Cleura Cloud",
+		Attributes: map[string]schema.Attribute{
+			"domain_id": schema.StringAttribute{
+				Description: "Domain to list the roles of. Defaults to the domain_id of the provider.",
+				Optional:    true,
+				Computed:    true,
+			},
+			"name_regex": schema.StringAttribute{
+				Description: "Only return roles whose name matches the regular expression.",
+				Optional:    true,
+			},
+			"names": schema.SetAttribute{
+				Description: "Only return the roles with these names. Fails if any of them does not exist.",
+				Optional:    true,
+				ElementType: types.StringType,
+			},
+			"roles": schema.ListNestedAttribute{
+				Computed: true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							Computed: true,
+						},
+						"name": schema.StringAttribute{
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,93 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func plannedOpenstackUser(name string, projects map[string][]string) openstackUserResourceModel {
+	user := testUser(true, "", projects)
+	user.Id = types.StringUnknown()
+	user.Name = types.StringValue(name)
+	user.Password = types.StringUnknown()
+	return user
+}
+
+func countRequests(requests []string, request string) int {
+	n := 0
+	for _, r := range requests {
+		if r == request {
+			n++
+		}
+	}
+	return n
+}
+
+// TestPlanListsDomainOnce checks that planning many users lists the roles and projects of their
+// domain once, and that a project created by the provider is seen by the next plan.
+func TestPlanListsDomainOnce(t *testing.T) {
+	api := newFakeAPI()
+	api.projects["d/p1"] = openstackProjectResourceJson{Id: "p1", Name: "one", DomainId: "d"}
+	client := newFakeClient(t, api)
+	h := newResourceHarness(t, NewOpenstackUserResource(), client)
+
+	for _, name := range []string{"alice", "bob", "carol"} {
+		if diags := h.ModifyPlan(plannedOpenstackUser(name, map[string][]string{"p1": {"member"}})); diags.HasError() {
+			t.Fatalf("plan %s: %v", name, diags)
+		}
+	}
+	requests := api.Requests()
+	if n := countRequests(requests, "GET /accesscontrol/v1/openstack/d/roles"); n != 1 {
+		t.Errorf("roles were listed %d times, expected once", n)
+	}
+	if n := countRequests(requests, "GET /accesscontrol/v1/openstack/d/projects"); n != 1 {
+		t.Errorf("projects were listed %d times, expected once", n)
+	}
+
+	created, err := client.CreateProject(h.ctx, "d", openstackProjectResourceJson{Name: "two", Enabled: true})
+	if err != nil {
+		t.Fatalf("create project: %s", err)
+	}
+	if diags := h.ModifyPlan(plannedOpenstackUser("dave", map[string][]string{created.Id: {"member"}})); diags.HasError() {
+		t.Errorf("a project created by the provider is unknown to the next plan: %v", diags)
+	}
+
+	// A project created outside the provider is found by listing the projects again
+	api.projects["d/p3"] = openstackProjectResourceJson{Id: "p3", Name: "three", DomainId: "d"}
+	if diags := h.ModifyPlan(plannedOpenstackUser("erin", map[string][]string{"p3": {"member"}})); diags.HasError() {
+		t.Errorf("a project created outside the provider is unknown: %v", diags)
+	}
+}
+
+func TestPlanValidatesProjectsAndRoles(t *testing.T) {
+	api := newFakeAPI()
+	api.projects["d/p1"] = openstackProjectResourceJson{Id: "p1", Name: "one", DomainId: "d"}
+	h := newResourceHarness(t, NewOpenstackUserResource(), newFakeClient(t, api))
+
+	tests := []struct {
+		name     string
+		projects map[string][]string
+		summary  string
+		path     string
+		suffix   string
+	}{
+		{"unknown project", map[string][]string{"missing": {"member"}}, "Unknown project", `projects[Value({"id":"missing"`, ".id"},
+		{"unknown role", map[string][]string{"p1": {"member", "owner"}}, "Unknown role", `projects[Value({"id":"p1"`, `.roles[Value("owner")]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := h.ModifyPlan(plannedOpenstackUser("alice", tt.projects))
+			if len(diags.Errors()) != 1 {
+				t.Fatalf("expected one error, got %v", diags)
+			}
+			d := diags.Errors()[0]
+			withPath, ok := d.(diag.DiagnosticWithPath)
+			if d.Summary() != tt.summary || !ok || !strings.HasPrefix(withPath.Path().String(), tt.path) || !strings.HasSuffix(withPath.Path().String(), tt.suffix) {
+				t.Errorf("got %q at %v, want %q at %s...%s", d.Summary(), withPath, tt.summary, tt.path, tt.suffix)
+			}
+		})
+	}
+}
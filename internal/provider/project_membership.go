@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// projectMembership holds the roles of a user keyed by project ID.
+type projectMembership map[string]map[string]bool
+
+func newProjectMembership(projects []openstackUserCreateProject) projectMembership {
+	m := projectMembership{}
+	for _, p := range projects {
+		if m[p.Id] == nil {
+			m[p.Id] = map[string]bool{}
+		}
+		for _, r := range p.Roles {
+			m[p.Id][r] = true
+		}
+	}
+	return m
+}
+
+// projects returns the memberships sorted by project ID and role name.
+func (m projectMembership) projects() []openstackUserCreateProject {
+	result := []openstackUserCreateProject{}
+	for _, id := range sortedKeys(m) {
+		result = append(result, openstackUserCreateProject{Id: id, Roles: sortedKeys(m[id])})
+	}
+	return result
+}
+
+type membershipOpKind int
+
+const (
+	addProject membershipOpKind = iota
+	addRole
+	removeRole
+	removeProject
+)
+
+func (k membershipOpKind) String() string {
+	switch k {
+	case addProject:
+		return "add project"
+	case addRole:
+		return "add role"
+	case removeRole:
+		return "remove role"
+	case removeProject:
+		return "remove project"
+	}
+	return "unknown"
+}
+
+// membershipOp is a single change to the project memberships of a user.
+type membershipOp struct {
+	Kind      membershipOpKind
+	ProjectId string
+	Roles     []string
+}
+
+// diffProjectMembership returns the operations that turn current into planned. Additions are ordered
+// before removals so the user never loses access halfway through, and within each kind the operations
+// are sorted by project ID and role so the API calls are deterministic.
+func diffProjectMembership(current, planned projectMembership) []membershipOp {
+	var ops []membershipOp
+	for _, id := range sortedKeys(planned) {
+		currentRoles, ok := current[id]
+		if !ok {
+			if len(planned[id]) > 0 {
+				ops = append(ops, membershipOp{Kind: addProject, ProjectId: id, Roles: sortedKeys(planned[id])})
+			}
+			continue
+		}
+		for _, r := range sortedKeys(planned[id]) {
+			if !currentRoles[r] {
+				ops = append(ops, membershipOp{Kind: addRole, ProjectId: id, Roles: []string{r}})
+			}
+		}
+	}
+	for _, id := range sortedKeys(current) {
+		plannedRoles, ok := planned[id]
+		if !ok {
+			if len(current[id]) > 0 {
+				ops = append(ops, membershipOp{Kind: removeProject, ProjectId: id, Roles: sortedKeys(current[id])})
+			}
+			continue
+		}
+		for _, r := range sortedKeys(current[id]) {
+			if !plannedRoles[r] {
+				ops = append(ops, membershipOp{Kind: removeRole, ProjectId: id, Roles: []string{r}})
+			}
+		}
+	}
+	return ops
+}
+
+// applyProjectMembership executes the operations needed to go from current to planned. It returns
+// the memberships that are known to be in place, which equals planned unless an error is returned,
+// so the caller can write state for what succeeded.
+func (c *CleuraClient) applyProjectMembership(ctx context.Context, domainId string, user string, current, planned projectMembership) (projectMembership, error) {
+	applied := projectMembership{}
+	for id, roles := range current {
+		applied[id] = map[string]bool{}
+		for r := range roles {
+			applied[id][r] = true
+		}
+	}
+	for _, op := range diffProjectMembership(current, planned) {
+		// Stop between operations as well, a single request may complete just before the cancellation
+		if err := ctx.Err(); err != nil {
+			return applied, err
+		}
+		switch op.Kind {
+		case addProject:
+			update := openstackProjectUpdate{Projects: []openstackProjectAssignment{{ProjectId: op.ProjectId, Roles: op.Roles}}}
+			if err := c.AddUserToProject(ctx, domainId, user, update); err != nil {
+				return applied, fmt.Errorf("%s %s: %w", op.Kind, op.ProjectId, err)
+			}
+			applied[op.ProjectId] = map[string]bool{}
+			for _, r := range op.Roles {
+				applied[op.ProjectId][r] = true
+			}
+		case addRole:
+			if err := c.AddUserToProjectRole(ctx, domainId, user, op.ProjectId, op.Roles[0]); err != nil {
+				return applied, fmt.Errorf("%s %s in project %s: %w", op.Kind, op.Roles[0], op.ProjectId, err)
+			}
+			applied[op.ProjectId][op.Roles[0]] = true
+		case removeRole, removeProject:
+			for _, r := range op.Roles {
+				if err := c.RemoveUserFromProjectRole(ctx, domainId, user, op.ProjectId, r); err != nil {
+					return applied, fmt.Errorf("%s %s, removing role %s: %w", op.Kind, op.ProjectId, r, err)
+				}
+				delete(applied[op.ProjectId], r)
+			}
+			if op.Kind == removeProject {
+				delete(applied, op.ProjectId)
+			}
+		}
+	}
+	return applied, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func membership(projects map[string][]string) projectMembership {
+	var list []openstackUserCreateProject
+	for id, roles := range projects {
+		list = append(list, openstackUserCreateProject{Id: id, Roles: roles})
+	}
+	return newProjectMembership(list)
+}
+
+func TestDiffProjectMembership(t *testing.T) {
+	tests := []struct {
+		name             string
+		current, planned map[string][]string
+		want             []membershipOp
+	}{
+		{
+			name:    "unchanged",
+			current: map[string][]string{"p1": {"member"}},
+			planned: map[string][]string{"p1": {"member"}},
+		},
+		{
+			name:    "add project",
+			current: map[string][]string{},
+			planned: map[string][]string{"p1": {"reader", "member"}},
+			want:    []membershipOp{{Kind: addProject, ProjectId: "p1", Roles: []string{"member", "reader"}}},
+		},
+		{
+			name:    "remove project",
+			current: map[string][]string{"p1": {"member", "reader"}},
+			planned: map[string][]string{},
+			want:    []membershipOp{{Kind: removeProject, ProjectId: "p1", Roles: []string{"member", "reader"}}},
+		},
+		{
+			name:    "change roles",
+			current: map[string][]string{"p1": {"member"}},
+			planned: map[string][]string{"p1": {"reader"}},
+			want: []membershipOp{
+				{Kind: addRole, ProjectId: "p1", Roles: []string{"reader"}},
+				{Kind: removeRole, ProjectId: "p1", Roles: []string{"member"}},
+			},
+		},
+		{
+			name:    "additions before removals, sorted by project",
+			current: map[string][]string{"p2": {"member"}, "p3": {"member"}},
+			planned: map[string][]string{"p3": {"member", "reader"}, "p1": {"member"}},
+			want: []membershipOp{
+				{Kind: addProject, ProjectId: "p1", Roles: []string{"member"}},
+				{Kind: addRole, ProjectId: "p3", Roles: []string{"reader"}},
+				{Kind: removeProject, ProjectId: "p2", Roles: []string{"member"}},
+			},
+		},
+		{
+			name:    "project without roles",
+			current: map[string][]string{"p1": {}},
+			planned: map[string][]string{"p2": {}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffProjectMembership(membership(tt.current), membership(tt.planned))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyProjectMembership(t *testing.T) {
+	api := newFakeAPI()
+	client := newFakeClient(t, api)
+	current := map[string][]string{"p1": {"member"}, "p2": {"member"}}
+	planned := map[string][]string{"p1": {"member", "reader"}, "p3": {"member"}}
+	api.seedUser("d", "u", "user", current)
+
+	applied, err := client.applyProjectMembership(context.Background(), "d", "u", membership(current), membership(planned))
+	if err != nil {
+		t.Fatalf("apply: %s", err)
+	}
+	want := membership(planned).projects()
+	if !reflect.DeepEqual(applied.projects(), want) {
+		t.Errorf("applied %v, want %v", applied.projects(), want)
+	}
+	if got := api.roles("d", "u"); !reflect.DeepEqual(got, want) {
+		t.Errorf("API has %v, want %v", got, want)
+	}
+}
+
+func TestApplyProjectMembershipPartialFailure(t *testing.T) {
+	api := newFakeAPI()
+	client := newFakeClient(t, api)
+	current := map[string][]string{"p1": {"member"}, "p2": {"member"}}
+	planned := map[string][]string{"p1": {"member", "reader"}, "p3": {"member"}}
+	api.seedUser("d", "u", "user", current)
+	// p1 gets reader and p3 is added, then removing p2 fails
+	api.fail["DELETE /accesscontrol/v1/openstack/d/users/u/projects/p2/member"] = http.StatusInternalServerError
+
+	applied, err := client.applyProjectMembership(context.Background(), "d", "u", membership(current), membership(planned))
+	var apiErr *CleuraAPIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected the 500 from removing p2, got: %v", err)
+	}
+	want := membership(map[string][]string{"p1": {"member", "reader"}, "p2": {"member"}, "p3": {"member"}}).projects()
+	if !reflect.DeepEqual(applied.projects(), want) {
+		t.Errorf("applied %v, want %v", applied.projects(), want)
+	}
+	if got := api.roles("d", "u"); !reflect.DeepEqual(got, want) {
+		t.Errorf("API has %v, want %v", got, want)
+	}
+}
+
+func TestApplyProjectMembershipCancelled(t *testing.T) {
+	api := newFakeAPI()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := newFakeClient(t, api)
+	// The first operation completes, then the operation is cancelled before the next one is sent
+	client.Client.Transport = cancelAfterResponses(client.Client.Transport, cancel, 1)
+	current := map[string][]string{"p1": {"member"}}
+	planned := map[string][]string{"p2": {"member"}, "p3": {"member"}}
+	api.seedUser("d", "u", "user", current)
+
+	applied, err := client.applyProjectMembership(ctx, "d", "u", membership(current), membership(planned))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got: %v", err)
+	}
+	if n := len(api.Requests()); n != 1 {
+		t.Errorf("expected a single request before the cancellation, got %d", n)
+	}
+	want := membership(map[string][]string{"p1": {"member"}, "p2": {"member"}}).projects()
+	if !reflect.DeepEqual(applied.projects(), want) {
+		t.Errorf("applied %v, want %v", applied.projects(), want)
+	}
+	if got := api.roles("d", "u"); !reflect.DeepEqual(got, want) {
+		t.Errorf("API has %v, want %v", got, want)
+	}
+}
@@ -2,8 +2,8 @@ package provider
 
 import (
 	"context"
-	"net/http"
 	"os"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/path"
@@ -33,8 +33,22 @@ func New(version string) func() provider.Provider {
 type cleuraProviderModel struct {
 	Username types.String `tfsdk:"username"`
 	Password types.String `tfsdk:"password"`
+	Token    types.String `tfsdk:"token"`
 	Url      types.String `tfsdk:"api_url"`
+	Region   types.String `tfsdk:"region"`
 	DomainId types.String `tfsdk:"domain_id"`
+	// Two-factor authentication
+	TOTPSecret types.String `tfsdk:"totp_secret"`
+	// Profile in the credentials file
+	Profile types.String `tfsdk:"profile"`
+	// Retry policy
+	MaxRetries   types.Int64 `tfsdk:"max_retries"`
+	RetryMinWait types.Int64 `tfsdk:"retry_min_wait"`
+	RetryMaxWait types.Int64 `tfsdk:"retry_max_wait"`
+	// Seconds to wait for a response to a single request
+	RequestTimeout types.Int64 `tfsdk:"request_timeout"`
+	// Fail instead of recreating resources that disappeared
+	FailOnUnexpectedDisappearance types.Bool `tfsdk:"fail_on_unexpected_disappearance"`
 }
 
 type cleuraProvider struct {
@@ -73,12 +87,39 @@ func (p *cleuraProvider) Configure(ctx context.Context, req provider.ConfigureRe
 			"The provider cannot create the cleura API client as there is an unknown configuration value for the cleura API username. ")
 	}
 
+	if config.Token.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("token"),
+			"Unknown Cleura Token",
+			"The provider cannot create the cleura API client as there is an unknown configuration value for the cleura API token. ")
+	}
+
+	if config.TOTPSecret.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("totp_secret"),
+			"Unknown Cleura TOTP secret",
+			"The provider cannot create the cleura API client as there is an unknown configuration value for the cleura API TOTP secret. ")
+	}
+
+	if config.Profile.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("profile"),
+			"Unknown Cleura profile",
+			"The provider cannot create the cleura API client as there is an unknown configuration value for the cleura credentials profile. ")
+	}
+
 	if config.Url.IsUnknown() {
 		resp.Diagnostics.AddAttributeError(
 			path.Root("api_url"),
 			"Unknown Cleura Url",
 			"The provider cannot create the cleura API client as there is an unknown configuration value for the cleura API password. ")
 	}
+	if config.Region.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("region"),
+			"Unknown Cleura Region",
+			"The provider cannot create the cleura API client as there is an unknown configuration value for the cleura region. ")
+	}
 	if config.DomainId.IsUnknown() {
 		resp.Diagnostics.AddAttributeError(
 			path.Root("domain_id"),
@@ -90,53 +131,133 @@ func (p *cleuraProvider) Configure(ctx context.Context, req provider.ConfigureRe
 		return
 	}
 
-	username := os.Getenv("CLEURA_USER")
-	password := os.Getenv("CLEURA_PW")
-	api_url := os.Getenv("CLEURA_URL")
-	domain_id := os.Getenv("CLEURA_DOMAIN_ID")
-
-	if !config.Username.IsNull() {
-		username = config.Username.ValueString()
+	profileName := os.Getenv("CLEURA_PROFILE")
+	if !config.Profile.IsNull() {
+		profileName = config.Profile.ValueString()
 	}
-
-	if !config.Password.IsNull() {
-		password = config.Password.ValueString()
+	profile, err := loadCredentialsProfile(profileName)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("profile"),
+			"Unable to read cleura credentials profile",
+			"The provider cannot create the cleura API client as the credentials file could not be read. "+
+				"Error: "+err.Error())
+		return
 	}
-
-	if !config.Url.IsNull() {
-		api_url = config.Url.ValueString()
+	if profile != nil && profileName == "" {
+		tflog.Info(ctx, "Using the default credentials profile in "+profile.Path)
 	}
 
-	if !config.DomainId.IsNull() {
-		domain_id = config.DomainId.ValueString()
-	}
+	// The provider configuration takes precedence over environment variables, which take precedence over the profile.
+	// The password and token are taken together from the first of them that sets either, the username too
+	// unless the provider configuration sets it.
+	settings := newProviderSettings(profile)
+	username, password, token := settings.resolveCredentials(config.Username, config.Password, config.Token)
+	totp_secret := settings.resolve("totp_secret", config.TOTPSecret, "CLEURA_TOTP_SECRET")
+	otp := os.Getenv("CLEURA_OTP")
+	api_url := settings.resolve("api_url", config.Url, "CLEURA_URL")
+	region := settings.resolve("region", config.Region, "CLEURA_REGION")
+	domain_id := settings.resolve("domain_id", config.DomainId, "CLEURA_DOMAIN_ID")
 
 	if username == "" {
 		resp.Diagnostics.AddAttributeError(
 			path.Root("username"),
 			"Missing cleura API username",
-			"The provider cannot create the cleura API client as there is a missing or empty value for the cleura API username. ")
+			"The provider cannot create the cleura API client as there is a missing or empty value for the cleura API username. "+
+				"Looked in "+settings.searched("CLEURA_USER")+". ")
 	}
 
-	if password == "" {
+	if password == "" && token == "" {
 		resp.Diagnostics.AddAttributeError(
 			path.Root("password"),
 			"Missing cleura API password",
-			"The provider cannot create the cleura API client as there is a missing or empty value for the cleura API username. ")
+			"The provider cannot create the cleura API client as there is a missing or empty value for the cleura API password. "+
+				"Set password or CLEURA_PW to log in, or token or CLEURA_TOKEN to use a token issued elsewhere. "+
+				"Looked in "+settings.searched("CLEURA_PW")+". ")
+	}
+
+	if password != "" && token != "" {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("token"),
+			"Conflicting cleura API credentials",
+			"Both a password, from "+settings.source("password")+", and a token, from "+settings.source("token")+", are set. "+
+				"Set only the password to let the provider log in, or only the token to use a token issued elsewhere. ")
+	}
+
+	if totp_secret != "" {
+		if _, err := totpCode(totp_secret, time.Now()); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("totp_secret"),
+				"Invalid cleura API TOTP secret",
+				"The TOTP secret from "+settings.source("totp_secret")+" must be the base32 encoded secret shown when two-factor authentication was enabled. "+
+					"Error: "+err.Error())
+		}
 	}
 
-	if api_url == "" {
+	// api_url overrides the endpoint of the region, without either the public cloud is used
+	if api_url == "" && region != "" {
+		endpoint, err := endpointForRegion(region)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("region"),
+				"Invalid cleura region",
+				"The region from "+settings.source("region")+" is not known. "+
+					"Error: "+err.Error())
+		}
+		api_url = endpoint
+		settings.sources["api_url"] = "region " + region
+	}
+	if api_url == "" && region == "" {
+		api_url = defaultApiUrl
+		settings.sources["api_url"] = "the default"
+	}
+	if err := validateApiUrl(api_url); err != nil && api_url != "" {
 		resp.Diagnostics.AddAttributeError(
 			path.Root("api_url"),
-			"Missing cleura API url",
-			"The provider cannot create the cleura API client as there is a missing or empty value for the cleura API password. ")
+			"Invalid cleura API url",
+			"The api_url from "+settings.source("api_url")+" is not valid. "+
+				"Error: "+err.Error())
 	}
 
 	if domain_id == "" {
 		resp.Diagnostics.AddAttributeError(
 			path.Root("domain_id"),
 			"Missing cleura API domain_id",
-			"The provider cannot create the cleura API client as there is a missing or empty value for the cleura API password. ")
+			"The provider cannot create the cleura API client as there is a missing or empty value for the cleura API domain_id. "+
+				"Looked in "+settings.searched("CLEURA_DOMAIN_ID")+". ")
+	}
+
+	retry := defaultRetryPolicy
+	if !config.MaxRetries.IsNull() {
+		retry.MaxRetries = int(config.MaxRetries.ValueInt64())
+	}
+	if !config.RetryMinWait.IsNull() {
+		retry.MinWait = time.Duration(config.RetryMinWait.ValueInt64()) * time.Second
+	}
+	if !config.RetryMaxWait.IsNull() {
+		retry.MaxWait = time.Duration(config.RetryMaxWait.ValueInt64()) * time.Second
+	}
+	requestTimeout := 60 * time.Second
+	if !config.RequestTimeout.IsNull() {
+		requestTimeout = time.Duration(config.RequestTimeout.ValueInt64()) * time.Second
+	}
+	if requestTimeout <= 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("request_timeout"),
+			"Invalid request_timeout",
+			"request_timeout must be greater than 0. ")
+	}
+	if retry.MaxRetries < 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("max_retries"),
+			"Invalid max_retries",
+			"max_retries can not be negative. ")
+	}
+	if retry.MinWait < 0 || retry.MaxWait < retry.MinWait {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("retry_max_wait"),
+			"Invalid retry wait",
+			"retry_min_wait can not be negative and retry_max_wait must be greater than or equal to retry_min_wait. ")
 	}
 
 	if resp.Diagnostics.HasError() {
@@ -147,25 +268,49 @@ func (p *cleuraProvider) Configure(ctx context.Context, req provider.ConfigureRe
 	ctx = tflog.SetField(ctx, "cleura_password", password)
 	ctx = tflog.SetField(ctx, "cleura url", api_url)
 	ctx = tflog.SetField(ctx, "cleura domain_id", domain_id)
-	ctx = tflog.MaskFieldValuesWithFieldKeys(ctx, "cleura_password")
+	ctx = tflog.SetField(ctx, "cleura_token", token)
+	ctx = tflog.SetField(ctx, "cleura_totp_secret", totp_secret)
+	ctx = tflog.MaskFieldValuesWithFieldKeys(ctx, "cleura_password", "cleura_token", "cleura_totp_secret")
 
 	tflog.Debug(ctx, "Creating Cleura client")
 
-	client := &CleuraClient{}
-	client.User = username
-	client.Password = password
-	client.Url = api_url
-	client.DomainId = domain_id
-	client.Client = &http.Client{}
-	err := client.Login(ctx)
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Unable to login to Cleura cloud",
-			"An unexpected error occurred when creating the CleuraClient. "+
-				"Error: "+err.Error(),
-		)
-		return
+	client := NewCleuraClient(username, password, api_url, domain_id, requestTimeout, retry)
+	client.FailOnUnexpectedDisappearance = config.FailOnUnexpectedDisappearance.ValueBool()
+	client.OTP = otp
+	client.TOTPSecret = totp_secret
+	if token != "" {
+		// The login exchange is skipped, but the token is checked so a bad one fails here rather than in the first resource
+		client.useToken(token)
+		if err := client.ValidateToken(ctx); err != nil {
+			if isUnreachable(err) {
+				addUnreachableError(&resp.Diagnostics, api_url, settings.source("api_url"), err)
+				return
+			}
+			resp.Diagnostics.AddAttributeError(
+				path.Root("token"),
+				"Invalid Cleura API token",
+				"The token from "+settings.source("token")+" was not accepted by the Cleura API, it may have expired or been issued for another user than "+username+" from "+settings.source("username")+". "+
+					"Error: "+err.Error(),
+			)
+			return
+		}
+	} else {
+		err := client.Login(ctx)
+		if isUnreachable(err) {
+			addUnreachableError(&resp.Diagnostics, api_url, settings.source("api_url"), err)
+			return
+		}
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Unable to login to Cleura cloud",
+				"An unexpected error occurred when creating the CleuraClient. "+
+					"Tried to log in as "+username+" from "+settings.source("username")+" with the password from "+settings.source("password")+". "+
+					"Error: "+err.Error(),
+			)
+			return
+		}
 	}
+	trackClient(client)
 	resp.DataSourceData = client
 	resp.ResourceData = client
 
@@ -187,16 +332,54 @@ func (p *cleuraProvider) Schema(_ context.Context, _ provider.SchemaRequest, res
 				Optional:    true,
 				Sensitive:   true,
 			},
+			"token": schema.StringAttribute{
+				Description: "API token issued outside the provider, used instead of logging in with password. The token is neither renewed nor revoked by the provider. May also be provided via CLEURA_TOKEN environment variable.",
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"totp_secret": schema.StringAttribute{
+				Description: "Base32 encoded TOTP secret used to answer the two-factor challenge of accounts with two-factor login enabled. May also be provided via CLEURA_TOTP_SECRET environment variable. A one-time code can instead be provided via CLEURA_OTP, but then the provider can not log in again when the token expires.",
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"profile": schema.StringAttribute{
+				Description: "Profile in the credentials file ~/.config/cleura/credentials to read username, password, token, totp_secret, api_url, region and domain_id from. Values set in the provider configuration or environment variables take precedence, the password and token are read together from the first of them that sets either, as is the username unless the provider configuration sets it. Defaults to the default profile when the file has one. May also be provided via CLEURA_PROFILE environment variable.",
+				Optional:    true,
+			},
 			"api_url": schema.StringAttribute{
-				Description: "Url for Cleura API. May also be provided via CLEURA_URL environment variable.",
+				Description: "Url for Cleura API, without a trailing slash. Overrides the endpoint of region. Defaults to https://rest.cleura.cloud. May also be provided via CLEURA_URL environment variable.",
 				Optional:    true,
 				Sensitive:   false,
 			},
+			"region": schema.StringAttribute{
+				Description: "Cleura region, such as Sto2, Kna1, Fra1 or Sto-Com, used to select the API endpoint when api_url is not set. May also be provided via CLEURA_REGION environment variable.",
+				Optional:    true,
+			},
 			"domain_id": schema.StringAttribute{
 				Description: "DomainId for Cleura API. May also be provided via CLEURA_DOMAIN_ID environment variable.",
 				Optional:    true,
 				Sensitive:   false,
 			},
+			"fail_on_unexpected_disappearance": schema.BoolAttribute{
+				Description: "Fail the refresh when a resource is no longer found in Cleura, instead of removing it from state and planning to create it again. Defaults to false.",
+				Optional:    true,
+			},
+			"max_retries": schema.Int64Attribute{
+				Description: "Maximum number of retries for requests that fail with a transient error or are rate limited. Defaults to 3.",
+				Optional:    true,
+			},
+			"request_timeout": schema.Int64Attribute{
+				Description: "Number of seconds a single request to the Cleura API may take, reading the response included. Every retry gets the full timeout. Defaults to 60.",
+				Optional:    true,
+			},
+			"retry_min_wait": schema.Int64Attribute{
+				Description: "Minimum number of seconds to wait between retries. Defaults to 1.",
+				Optional:    true,
+			},
+			"retry_max_wait": schema.Int64Attribute{
+				Description: "Maximum number of seconds to wait between retries, also when the API asks for a longer wait with Retry-After. Defaults to 30.",
+				Optional:    true,
+			},
 		},
 	}
 }
@@ -206,6 +389,8 @@ func (p *cleuraProvider) DataSources(_ context.Context) []func() datasource.Data
 	return []func() datasource.DataSource{
 		NewOpenstackUserDataSource,
 		NewCCPUserDataSource,
+		NewOpenstackProjectsDataSource,
+		NewOpenstackRolesDataSource,
 	}
 }
 
@@ -214,6 +399,8 @@ func (p *cleuraProvider) Resources(_ context.Context) []func() resource.Resource
 	return []func() resource.Resource{
 		NewOpenstackUserResource,
 		NewCCPUserResource,
+		NewOpenstackProjectResource,
+		NewOpenstackUsersResource,
 	}
 }
 
@@ -0,0 +1,108 @@
+package provider
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// readCases creates one object of every resource against the fake API.
+var readCases = []struct {
+	name   string
+	create func() resource.Resource
+	model  any
+}{
+	{"openstack_user", NewOpenstackUserResource, plannedOpenstackUser("alice", map[string][]string{"p1": {"member"}})},
+	{"openstack_users", NewOpenstackUsersResource, testUsers(map[string]openstackUsersUser{"alice": plannedUser("a")})},
+	{"openstack_project", NewOpenstackProjectResource, openstackProjectResourceModel{
+		Id:       types.StringUnknown(),
+		Name:     types.StringValue("project"),
+		DomainId: types.StringValue("d"),
+		Enabled:  types.BoolValue(true),
+	}},
+	{"ccp_user", NewCCPUserResource, ccpUserWithProjects("project", "p1")},
+}
+
+// readRequest returns the request a Read of the object in state sends.
+func readRequest(t *testing.T, h *resourceHarness, api *fakeAPI, state tfsdk.State) string {
+	t.Helper()
+	api.Requests()
+	if _, err := h.Read(state); err != nil {
+		t.Fatalf("read: %s", err)
+	}
+	var get string
+	for _, request := range api.Requests() {
+		if strings.HasPrefix(request, http.MethodGet+" ") {
+			get = request
+		}
+	}
+	if get == "" {
+		t.Fatal("read sent no GET request")
+	}
+	return get
+}
+
+// TestReadFailureKeepsState checks that an error other than not found, and not found with
+// fail_on_unexpected_disappearance set, fail the refresh and keep the object in state.
+func TestReadFailureKeepsState(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		strict bool
+	}{
+		{"bad request", http.StatusBadRequest, false},
+		{"forbidden", http.StatusForbidden, false},
+		{"not found with fail_on_unexpected_disappearance", http.StatusNotFound, true},
+	}
+	for _, rc := range readCases {
+		for _, tt := range tests {
+			t.Run(rc.name+"/"+tt.name, func(t *testing.T) {
+				api := newFakeAPI()
+				client := newFakeClient(t, api)
+				client.FailOnUnexpectedDisappearance = tt.strict
+				h := newResourceHarness(t, rc.create(), client)
+				state, err := h.Create(rc.model)
+				if err != nil {
+					t.Fatalf("create: %s", err)
+				}
+				api.fail[readRequest(t, h, api, state)] = tt.status
+
+				read, err := h.Read(state)
+				if err == nil {
+					t.Error("expected the refresh to fail")
+				}
+				if read.Raw.IsNull() || !read.Raw.Equal(state.Raw) {
+					t.Errorf("state changed from %s to %s", state.Raw, read.Raw)
+				}
+			})
+		}
+	}
+}
+
+// TestReadNotFoundRemoves checks that without fail_on_unexpected_disappearance an object that is
+// not found is removed from state, so it is planned to be created again.
+func TestReadNotFoundRemoves(t *testing.T) {
+	for _, rc := range readCases {
+		t.Run(rc.name, func(t *testing.T) {
+			api := newFakeAPI()
+			h := newResourceHarness(t, rc.create(), newFakeClient(t, api))
+			state, err := h.Create(rc.model)
+			if err != nil {
+				t.Fatalf("create: %s", err)
+			}
+			api.fail[readRequest(t, h, api, state)] = http.StatusNotFound
+
+			read, err := h.Read(state)
+			if err != nil {
+				t.Fatalf("read: %s", err)
+			}
+			if read.Raw.Equal(state.Raw) {
+				t.Error("the object that was not found is still in state")
+			}
+		})
+	}
+}
@@ -6,7 +6,10 @@ package provider
 import (
 	"context"
 	"fmt"
+	"maps"
+	"strconv"
 
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
@@ -32,10 +35,11 @@ type ccpUserResourceModel struct {
 	// Password  types.String `tfsdk:"password" json:"password"`
 	// IpRestrictions []string `tfsdk:"ip_restrictions"`
 	Privileges *ccpResourcePrivileges `tfsdk:"privileges" json:"privileges"`
+	Timeouts   timeouts.Value         `tfsdk:"timeouts" json:"-"`
 }
 type ccpResourcePrivileges struct {
-	Users     ccpUserResourceUserPrivilege       `tfsdk:"users"`
-	OpenStack ccpUserResourceOpenstackPrivileges `tfsdk:"openstack"`
+	Users     *ccpUserResourceUserPrivilege       `tfsdk:"users"`
+	OpenStack *ccpUserResourceOpenstackPrivileges `tfsdk:"openstack"`
 }
 type ccpUserResourceUserPrivilege struct {
 	Type types.String `tfsdk:"type"`
@@ -44,8 +48,7 @@ type ccpUserResourceUserPrivilege struct {
 type ccpUserResourceOpenstackPrivileges struct {
 	Type types.String `tfsdk:"type"`
 	// Meta              types.String           `tfsdk:"meta"`
-	// Uncomment when adding support for project privileges
-	// ProjectPrivileges []ccpUserResourceProjectPrivileges `tfsdk:"project_privileges"`
+	ProjectPrivileges []ccpUserResourceProjectPrivileges `tfsdk:"project_privileges"`
 }
 type ccpUserResourceProjectPrivileges struct {
 	ProjectId types.String `tfsdk:"project_id"`
@@ -103,20 +106,41 @@ type ccpUserResource struct {
 	Client *CleuraClient
 }
 
-func (c *ccpUserResource) GetJsonModel(obj ccpUserResourceModel) ccpUserResourceModelJson {
+// getCCPUserJson converts the resource model into the API representation.
+func getCCPUserJson(obj ccpUserResourceModel) ccpUserResourceModelJson {
 	result := ccpUserResourceModelJson{
-		Name:      obj.Name.ValueString(),
-		Email:     obj.Email.ValueString(),
-		FirstName: obj.FirstName.ValueString(),
-		LastName:  obj.LastName.ValueString(),
-		Privileges: &ccpResourcePrivilegesJson{
-			Users: ccpUserResourcePrivilegeJson{
-				Type: obj.Privileges.Users.Type.ValueString(),
-			},
-			OpenStack: ccpUserOpenstackPrivilegesJson{
-				Type: obj.Privileges.OpenStack.Type.ValueString(),
-			},
-		},
+		Name:       obj.Name.ValueString(),
+		Email:      obj.Email.ValueString(),
+		FirstName:  obj.FirstName.ValueString(),
+		LastName:   obj.LastName.ValueString(),
+		Privileges: &ccpResourcePrivilegesJson{},
+	}
+	if obj.Privileges == nil {
+		return result
+	}
+	if obj.Privileges.Users != nil {
+		result.Privileges.Users = ccpUserResourcePrivilegeJson{
+			Type: obj.Privileges.Users.Type.ValueString(),
+		}
+	}
+	if obj.Privileges.OpenStack != nil {
+		result.Privileges.OpenStack = ccpUserOpenstackPrivilegesJson{
+			Type:              obj.Privileges.OpenStack.Type.ValueString(),
+			ProjectPrivileges: getProjectPrivilegesJson(obj.Privileges.OpenStack.ProjectPrivileges),
+		}
+	}
+	return result
+}
+
+// getProjectPrivilegesJson converts the project privileges of the resource model into the API representation.
+func getProjectPrivilegesJson(privileges []ccpUserResourceProjectPrivileges) []ccpUserResourceProjectPrivilegesJson {
+	var result []ccpUserResourceProjectPrivilegesJson
+	for _, p := range privileges {
+		result = append(result, ccpUserResourceProjectPrivilegesJson{
+			ProjectId: p.ProjectId.ValueString(),
+			DomainId:  p.DomainId.ValueString(),
+			Type:      p.Type.ValueString(),
+		})
 	}
 	return result
 }
@@ -171,28 +195,36 @@ func (c *ccpUserResource) Schema(ctx context.Context, req resource.SchemaRequest
 							// "meta": schema.StringAttribute{
 							// 	Optional: true,
 							// },
-							// Uncomment when adding support for project privileges
-							// "project_privileges": schema.ListNestedAttribute{
-							// 	Optional: true,
-							// 	NestedObject: schema.NestedAttributeObject{
-							// 		Attributes: map[string]schema.Attribute{
-							// 			"project_id": schema.StringAttribute{
-							// 				Required: true,
-							// 			},
-							// 			"domain_id": schema.StringAttribute{
-							// 				Required: true,
-							// 			},
-							// 			"type": schema.StringAttribute{
-							// 				Required: true,
-							// 			},
-							// 		},
-							// 	},
-							// },
+							"project_privileges": schema.SetNestedAttribute{
+								Optional:    true,
+								Description: "Per project privileges, used when the openstack privilege type is project.",
+								NestedObject: schema.NestedAttributeObject{
+									Attributes: map[string]schema.Attribute{
+										"project_id": schema.StringAttribute{
+											Required: true,
+										},
+										"domain_id": schema.StringAttribute{
+											Required: true,
+										},
+										"type": schema.StringAttribute{
+											Required: true,
+										},
+									},
+								},
+							},
 						},
 					},
 				},
 			},
 		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Read:   true,
+				Update: true,
+				Delete: true,
+			}),
+		},
 	}
 }
 
@@ -217,17 +249,36 @@ func (c *ccpUserResource) Create(ctx context.Context, req resource.CreateRequest
 	if resp.Diagnostics.HasError() {
 		return
 	}
+	createTimeout, diags := plan.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := withOperationTimeout(ctx, createTimeout)
+	defer cancel()
 
 	result, err := c.Client.CreateCCPUser(ctx, plan)
 	if err != nil {
+		if addCancelledError(ctx, &resp.Diagnostics, "Creating CCP user "+plan.Name.ValueString(), err) {
+			return
+		}
 		tflog.Error(ctx, fmt.Sprintf("failed to create user, error: %s", err.Error()))
+		if IsConflict(err) {
+			resp.Diagnostics.AddError("CCP user already exists", fmt.Sprintf("CCP user %s already exists, import it instead. error: %s", plan.Name.ValueString(), err.Error()))
+			return
+		}
 		resp.Diagnostics.AddError("Failed to create user", fmt.Sprintf("error: %s", err.Error()))
 		return
 	}
-	plan.Id = types.StringValue(result.Id.ValueString())
+	plan.Id = result.Id
 	tflog.Trace(ctx, "created user resource")
 
+	err = c.waitForCCPUser(ctx, plan)
+	// The user exists, so it is written to state even if it has not become consistent
 	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+	if err != nil {
+		addConsistencyError(ctx, &resp.Diagnostics, "Creating CCP user "+plan.Name.ValueString(), err)
+	}
 }
 
 func (c *ccpUserResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
@@ -237,18 +288,23 @@ func (c *ccpUserResource) Read(ctx context.Context, req resource.ReadRequest, re
 	if resp.Diagnostics.HasError() {
 		return
 	}
-	exist, err := c.Client.DoesCCPUserExist(ctx, state.Name.ValueString())
-	if err != nil {
-		resp.Diagnostics.AddError("Failed to check if CCP user already exists", err.Error())
+	readTimeout, diags := state.Timeouts.Read(ctx, defaultReadTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
-	if !exist {
+	ctx, cancel := withOperationTimeout(ctx, readTimeout)
+	defer cancel()
+	userResponse, err := c.Client.GetCCPUserResource(ctx, state.Name.ValueString())
+	if IsNotFound(err) {
 		// The user has been removed from outside Terraform, recreate it
-		resp.State.RemoveResource(ctx)
-		resp.Diagnostics.AddWarning("Cleura CCP User resource has been deleted outside terraform", "New resource will be created")
+		c.Client.removeDisappeared(ctx, resp, "Cleura CCP User resource has been deleted outside terraform")
 		return
 	}
-	userResponse, err := c.Client.GetCCPUserResource(ctx, state.Name.ValueString())
 	if err != nil {
+		if addCancelledError(ctx, &resp.Diagnostics, "Reading CCP user "+state.Name.ValueString(), err) {
+			return
+		}
 		resp.Diagnostics.AddError(
 			"Error Reading user resource",
 			"Could not read user resource named: "+state.Name.ValueString()+": "+err.Error(),
@@ -256,6 +312,7 @@ func (c *ccpUserResource) Read(ctx context.Context, req resource.ReadRequest, re
 		return
 	}
 	tflog.Debug(ctx, fmt.Sprintf("userResponse: %+v", userResponse))
+	userResponse.Timeouts = state.Timeouts
 
 	// Set refreshed state
 	diags = resp.State.Set(ctx, &userResponse)
@@ -276,18 +333,28 @@ func (c *ccpUserResource) Update(ctx context.Context, req resource.UpdateRequest
 	if resp.Diagnostics.HasError() {
 		return
 	}
-	updateModel := c.GetJsonModel(plan)
+	updateTimeout, diags := plan.Timeouts.Update(ctx, defaultUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := withOperationTimeout(ctx, updateTimeout)
+	defer cancel()
+	updateModel := getCCPUserJson(plan)
 	err := c.Client.UpdateCCPUser(ctx, ccpUserUpdate{User: updateModel})
 	if err != nil {
+		if addCancelledError(ctx, &resp.Diagnostics, "Updating CCP user "+plan.Name.ValueString(), err) {
+			return
+		}
 		resp.Diagnostics.AddError("Failed to update CCP user", err.Error())
 		return
 	}
-	diags := resp.State.Set(ctx, plan)
+	err = c.waitForCCPUser(ctx, plan)
+	diags = resp.State.Set(ctx, plan)
 	resp.Diagnostics.Append(diags...)
-	if resp.Diagnostics.HasError() {
-		return
+	if err != nil {
+		addConsistencyError(ctx, &resp.Diagnostics, "Updating CCP user "+plan.Name.ValueString(), err)
 	}
-
 }
 
 func (c *ccpUserResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
@@ -297,8 +364,18 @@ func (c *ccpUserResource) Delete(ctx context.Context, req resource.DeleteRequest
 	if resp.Diagnostics.HasError() {
 		return
 	}
+	deleteTimeout, diags := state.Timeouts.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := withOperationTimeout(ctx, deleteTimeout)
+	defer cancel()
 	err := c.Client.DeleteCCPUser(ctx, state.Name.ValueString())
 	if err != nil {
+		if addCancelledError(ctx, &resp.Diagnostics, "Deleting CCP user "+state.Name.ValueString(), err) {
+			return
+		}
 		resp.Diagnostics.AddError(
 			"Error Deleting Cleura CCP user",
 			"Could not delete Cleura CCP user, unexpected error: "+err.Error(),
@@ -307,6 +384,77 @@ func (c *ccpUserResource) Delete(ctx context.Context, req resource.DeleteRequest
 	}
 }
 
+// ImportState accepts either the login name or the numeric ID of the CCP user. Read looks the
+// user up by name, so the name is resolved here and Read fills in the rest of the state.
 func (c *ccpUserResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+	name := req.ID
+	if _, err := strconv.ParseUint(req.ID, 10, 64); err == nil {
+		found, ok, err := c.Client.FindCCPUserById(ctx, req.ID)
+		if err != nil {
+			if addCancelledError(ctx, &resp.Diagnostics, "Importing CCP user "+req.ID, err) {
+				return
+			}
+			resp.Diagnostics.AddError("Failed to look up CCP user by id", err.Error())
+			return
+		}
+		// A login name may consist of digits only, fall back to treating the ID as a name
+		if ok {
+			name = found
+		}
+	}
+	user, err := c.Client.GetCCPUserResource(ctx, name)
+	if err != nil {
+		if addCancelledError(ctx, &resp.Diagnostics, "Importing CCP user "+req.ID, err) {
+			return
+		}
+		resp.Diagnostics.AddError(
+			"Failed to import CCP user",
+			fmt.Sprintf("No CCP user with name or id %q could be read: %s", req.ID, err.Error()),
+		)
+		return
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), user.Id)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), user.Name)...)
+}
+
+// waitForCCPUser polls the CCP user until the API returns the privileges of plan.
+func (c *ccpUserResource) waitForCCPUser(ctx context.Context, plan ccpUserResourceModel) error {
+	return waitForConsistency(ctx, func(ctx context.Context) ([]string, error) {
+		observed, err := c.Client.GetCCPUserResource(ctx, plan.Name.ValueString())
+		if IsNotFound(err) {
+			return []string{"CCP user is not visible yet"}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return ccpUserDifferences(plan, observed), nil
+	})
+}
+
+// ccpUserDifferences lists how the privileges of the CCP user read from the API differ from plan.
+// Project privileges are a set, so they are compared regardless of their order.
+func ccpUserDifferences(plan, observed ccpUserResourceModel) []string {
+	expected := getCCPUserJson(plan).Privileges
+	actual := getCCPUserJson(observed).Privileges
+	var differences []string
+	if expected.Users != actual.Users {
+		differences = append(differences, fmt.Sprintf("users privilege is %q, expected %q", actual.Users.Type, expected.Users.Type))
+	}
+	if expected.OpenStack.Type != actual.OpenStack.Type {
+		differences = append(differences, fmt.Sprintf("openstack privilege is %q, expected %q", actual.OpenStack.Type, expected.OpenStack.Type))
+	}
+	expectedProjects := projectPrivilegeSet(expected.OpenStack.ProjectPrivileges)
+	actualProjects := projectPrivilegeSet(actual.OpenStack.ProjectPrivileges)
+	if !maps.Equal(expectedProjects, actualProjects) {
+		differences = append(differences, fmt.Sprintf("project privileges are %v, expected %v", actual.OpenStack.ProjectPrivileges, expected.OpenStack.ProjectPrivileges))
+	}
+	return differences
+}
+
+func projectPrivilegeSet(privileges []ccpUserResourceProjectPrivilegesJson) map[ccpUserResourceProjectPrivilegesJson]bool {
+	set := map[ccpUserResourceProjectPrivilegesJson]bool{}
+	for _, p := range privileges {
+		set[p] = true
+	}
+	return set
 }
@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func ccpUserWithProjects(openstackType string, projects ...string) ccpUserResourceModel {
+	privileges := []ccpUserResourceProjectPrivileges{}
+	for _, project := range projects {
+		privileges = append(privileges, ccpUserResourceProjectPrivileges{
+			ProjectId: types.StringValue(project),
+			DomainId:  types.StringValue("d"),
+			Type:      types.StringValue("full"),
+		})
+	}
+	return ccpUserResourceModel{
+		Id:        types.StringUnknown(),
+		Name:      types.StringValue("alice"),
+		Email:     types.StringValue("alice@example.com"),
+		FirstName: types.StringNull(),
+		LastName:  types.StringNull(),
+		Privileges: &ccpResourcePrivileges{
+			OpenStack: &ccpUserResourceOpenstackPrivileges{Type: types.StringValue(openstackType), ProjectPrivileges: privileges},
+		},
+		Timeouts: nullTimeouts(),
+	}
+}
+
+func TestCCPUserDifferences(t *testing.T) {
+	tests := []struct {
+		name        string
+		plan        ccpUserResourceModel
+		observed    ccpUserResourceModel
+		differences int
+	}{
+		{"equal", ccpUserWithProjects("project", "p1", "p2"), ccpUserWithProjects("project", "p1", "p2"), 0},
+		{"other order", ccpUserWithProjects("project", "p1", "p2"), ccpUserWithProjects("project", "p2", "p1"), 0},
+		{"missing project", ccpUserWithProjects("project", "p1", "p2"), ccpUserWithProjects("project", "p1"), 1},
+		{"other type", ccpUserWithProjects("project", "p1"), ccpUserWithProjects("full", "p1"), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ccpUserDifferences(tt.plan, tt.observed); len(got) != tt.differences {
+				t.Errorf("got differences %v, want %d", got, tt.differences)
+			}
+		})
+	}
+}
+
+// TestCCPUserProjectPrivilegesOrder checks that project privileges returned by the API in another
+// order than configured read back as the same set.
+func TestCCPUserProjectPrivilegesOrder(t *testing.T) {
+	api := newFakeAPI()
+	h := newResourceHarness(t, NewCCPUserResource(), newFakeClient(t, api))
+	state, err := h.Create(ccpUserWithProjects("project", "p1", "p2"))
+	if err != nil {
+		t.Fatalf("create: %s", err)
+	}
+	user := api.ccpUsers["alice"]
+	privileges := user.Privileges.OpenStack.ProjectPrivileges
+	privileges[0], privileges[1] = privileges[1], privileges[0]
+
+	read, err := h.Read(state)
+	if err != nil {
+		t.Fatalf("read: %s", err)
+	}
+	if !read.Raw.Equal(state.Raw) {
+		t.Errorf("reordered project privileges changed state from %s to %s", state.Raw, read.Raw)
+	}
+}
@@ -0,0 +1,159 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// resourceHarness calls the CRUD and import methods of a resource the way Terraform does, with
+// plans and states built from Go models.
+type resourceHarness struct {
+	t        *testing.T
+	resource resource.Resource
+	schema   schema.Schema
+	// ctx is passed to every call, tests replace it to cancel an operation
+	ctx context.Context
+}
+
+// newResourceHarness configures r with client.
+func newResourceHarness(t *testing.T, r resource.Resource, client *CleuraClient) *resourceHarness {
+	t.Helper()
+	ctx := context.Background()
+	schemaResp := resource.SchemaResponse{}
+	r.Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+	if schemaResp.Diagnostics.HasError() {
+		t.Fatalf("schema: %v", schemaResp.Diagnostics)
+	}
+	configureResp := resource.ConfigureResponse{}
+	r.(resource.ResourceWithConfigure).Configure(ctx, resource.ConfigureRequest{ProviderData: client}, &configureResp)
+	if configureResp.Diagnostics.HasError() {
+		t.Fatalf("configure: %v", configureResp.Diagnostics)
+	}
+	return &resourceHarness{t: t, resource: r, schema: schemaResp.Schema, ctx: ctx}
+}
+
+// emptyState returns a state without a resource.
+func (h *resourceHarness) emptyState() tfsdk.State {
+	return tfsdk.State{Schema: h.schema, Raw: tftypes.NewValue(h.schema.Type().TerraformType(context.Background()), nil)}
+}
+
+// state returns a state holding model.
+func (h *resourceHarness) state(model any) tfsdk.State {
+	h.t.Helper()
+	state := h.emptyState()
+	if diags := state.Set(context.Background(), model); diags.HasError() {
+		h.t.Fatalf("set state: %v", diags)
+	}
+	return state
+}
+
+// Create applies a plan for model and returns the resulting state.
+func (h *resourceHarness) Create(model any) (tfsdk.State, error) {
+	h.t.Helper()
+	planned := h.state(model)
+	req := resource.CreateRequest{
+		Config: tfsdk.Config{Schema: planned.Schema, Raw: planned.Raw},
+		Plan:   tfsdk.Plan{Schema: planned.Schema, Raw: planned.Raw},
+	}
+	resp := resource.CreateResponse{State: h.emptyState()}
+	h.resource.Create(h.ctx, req, &resp)
+	return resp.State, diagnosticsError(resp.Diagnostics)
+}
+
+// Update applies a plan for model to prior and returns the resulting state.
+func (h *resourceHarness) Update(prior tfsdk.State, model any) (tfsdk.State, error) {
+	h.t.Helper()
+	planned := h.state(model)
+	req := resource.UpdateRequest{
+		Config: tfsdk.Config{Schema: planned.Schema, Raw: planned.Raw},
+		Plan:   tfsdk.Plan{Schema: planned.Schema, Raw: planned.Raw},
+		State:  prior,
+	}
+	resp := resource.UpdateResponse{State: prior}
+	h.resource.Update(h.ctx, req, &resp)
+	return resp.State, diagnosticsError(resp.Diagnostics)
+}
+
+// ModifyPlan runs the plan modification of a resource that is about to be created with model and
+// returns its diagnostics.
+func (h *resourceHarness) ModifyPlan(model any) diag.Diagnostics {
+	h.t.Helper()
+	planned := h.state(model)
+	plan := tfsdk.Plan{Schema: planned.Schema, Raw: planned.Raw}
+	req := resource.ModifyPlanRequest{
+		Config: tfsdk.Config{Schema: planned.Schema, Raw: planned.Raw},
+		Plan:   plan,
+		State:  h.emptyState(),
+	}
+	resp := resource.ModifyPlanResponse{Plan: plan}
+	h.resource.(resource.ResourceWithModifyPlan).ModifyPlan(h.ctx, req, &resp)
+	return resp.Diagnostics
+}
+
+// Read refreshes state.
+func (h *resourceHarness) Read(state tfsdk.State) (tfsdk.State, error) {
+	h.t.Helper()
+	resp := resource.ReadResponse{State: state}
+	h.resource.Read(h.ctx, resource.ReadRequest{State: state}, &resp)
+	return resp.State, diagnosticsError(resp.Diagnostics)
+}
+
+// Import imports id and reads the imported resource, as terraform import does.
+func (h *resourceHarness) Import(id string) (tfsdk.State, error) {
+	h.t.Helper()
+	resp := resource.ImportStateResponse{State: h.emptyState()}
+	h.resource.(resource.ResourceWithImportState).ImportState(h.ctx, resource.ImportStateRequest{ID: id}, &resp)
+	if err := diagnosticsError(resp.Diagnostics); err != nil {
+		return resp.State, err
+	}
+	return h.Read(resp.State)
+}
+
+// Delete destroys the resource in state.
+func (h *resourceHarness) Delete(state tfsdk.State) error {
+	h.t.Helper()
+	resp := resource.DeleteResponse{State: state}
+	h.resource.Delete(h.ctx, resource.DeleteRequest{State: state}, &resp)
+	return diagnosticsError(resp.Diagnostics)
+}
+
+// get decodes state into model.
+func (h *resourceHarness) get(state tfsdk.State, model any) {
+	h.t.Helper()
+	if diags := state.Get(context.Background(), model); diags.HasError() {
+		h.t.Fatalf("get state: %v", diags)
+	}
+}
+
+// diagnosticsError turns error diagnostics into an error, so tests can report them.
+func diagnosticsError(diags diag.Diagnostics) error {
+	if !diags.HasError() {
+		return nil
+	}
+	var messages []string
+	for _, d := range diags.Errors() {
+		messages = append(messages, d.Summary()+": "+d.Detail())
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
+
+// nullTimeouts returns an unset timeouts block for the models of resources that have one.
+func nullTimeouts() timeouts.Value {
+	return timeouts.Value{Object: types.ObjectNull(map[string]attr.Type{
+		"create": types.StringType,
+		"read":   types.StringType,
+		"update": types.StringType,
+		"delete": types.StringType,
+	})}
+}
@@ -0,0 +1,284 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &openstackProjectResource{}
+var _ resource.ResourceWithImportState = &openstackProjectResource{}
+
+// ==============
+// RESOURCE MODEL
+// ==============
+type openstackProjectResourceModel struct {
+	Id          types.String `tfsdk:"id"`
+	Name        types.String `tfsdk:"name"`
+	DomainId    types.String `tfsdk:"domain_id"`
+	Description types.String `tfsdk:"description"`
+	Enabled     types.Bool   `tfsdk:"enabled"`
+	ParentId    types.String `tfsdk:"parent_id"`
+	Tags        []string     `tfsdk:"tags"`
+}
+
+// ==============
+// JSON MODEL
+// ==============
+type openstackProjectRequestJson struct {
+	Project openstackProjectResourceJson `json:"project"`
+}
+type openstackProjectResourceJson struct {
+	Id          string   `json:"id,omitempty"`
+	Name        string   `json:"name"`
+	DomainId    string   `json:"domain_id,omitempty"`
+	Description string   `json:"description"`
+	Enabled     bool     `json:"enabled"`
+	ParentId    string   `json:"parent_id,omitempty"`
+	Tags        []string `json:"tags"`
+}
+
+func NewOpenstackProjectResource() resource.Resource {
+	return &openstackProjectResource{}
+}
+
+type openstackProjectResource struct {
+	Client *CleuraClient
+}
+
+// getProjectJson converts the resource model into the API representation.
+func getProjectJson(model openstackProjectResourceModel) openstackProjectResourceJson {
+	tags := model.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return openstackProjectResourceJson{
+		Name:        model.Name.ValueString(),
+		Description: model.Description.ValueString(),
+		Enabled:     model.Enabled.ValueBool(),
+		ParentId:    model.ParentId.ValueString(),
+		Tags:        tags,
+	}
+}
+
+// getProjectModel converts the API representation into the resource model.
+func getProjectModel(project openstackProjectResourceJson) openstackProjectResourceModel {
+	model := openstackProjectResourceModel{
+		Id:          types.StringValue(project.Id),
+		Name:        types.StringValue(project.Name),
+		DomainId:    types.StringValue(project.DomainId),
+		Description: types.StringNull(),
+		Enabled:     types.BoolValue(project.Enabled),
+		ParentId:    types.StringNull(),
+	}
+	if len(project.Description) > 0 {
+		model.Description = types.StringValue(project.Description)
+	}
+	// Keystone reports the domain as parent of top level projects
+	if len(project.ParentId) > 0 && project.ParentId != project.DomainId {
+		model.ParentId = types.StringValue(project.ParentId)
+	}
+	if len(project.Tags) > 0 {
+		model.Tags = project.Tags
+	}
+	return model
+}
+
+func (c *openstackProjectResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_openstack_project"
+}
+
+func (c *openstackProjectResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Creates an OpenStack project in Cleura Cloud",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"name": schema.StringAttribute{
+				Required: true,
+			},
+			"domain_id": schema.StringAttribute{
+				Description: "Domain the project is created in. Defaults to the domain_id of the provider. Changing it creates a new project.",
+				Optional:    true,
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"description": schema.StringAttribute{
+				Optional: true,
+			},
+			"enabled": schema.BoolAttribute{
+				Description: "Defaults to true.",
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(true),
+			},
+			"parent_id": schema.StringAttribute{
+				Description: "ID of the parent project. Changing it creates a new project.",
+				Optional:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"tags": schema.SetAttribute{
+				Optional:    true,
+				ElementType: types.StringType,
+			},
+		},
+	}
+}
+
+func (c *openstackProjectResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	client, ok := req.ProviderData.(*CleuraClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unable to cast ProviderData to *CleuraClient",
+			fmt.Sprintf("Expected *CleuraClient, got: %T", req.ProviderData),
+		)
+		return
+	}
+	c.Client = client
+}
+
+func (c *openstackProjectResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan openstackProjectResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	domainId := c.Client.domainFor(plan.DomainId)
+	result, err := c.Client.CreateProject(ctx, domainId, getProjectJson(plan))
+	if err != nil {
+		if addCancelledError(ctx, &resp.Diagnostics, "Creating project "+plan.Name.ValueString(), err) {
+			return
+		}
+		tflog.Error(ctx, fmt.Sprintf("failed to create project, error: %s", err.Error()))
+		if IsConflict(err) {
+			resp.Diagnostics.AddError("Project already exists", fmt.Sprintf("project %s already exists in domain %s, import it instead. error: %s", plan.Name.ValueString(), domainId, err.Error()))
+			return
+		}
+		resp.Diagnostics.AddError("Failed to create project", fmt.Sprintf("error: %s", err.Error()))
+		return
+	}
+	plan.Id = types.StringValue(result.Id)
+	plan.DomainId = types.StringValue(domainId)
+	tflog.Trace(ctx, "created project resource")
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (c *openstackProjectResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state openstackProjectResourceModel
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	domainId := c.Client.domainFor(state.DomainId)
+	project, err := c.Client.GetProject(ctx, domainId, state.Id.ValueString())
+	if addCancelledError(ctx, &resp.Diagnostics, "Reading project "+state.Id.ValueString(), err) {
+		return
+	}
+	if IsNotFound(err) {
+		// The project has been removed from outside Terraform, recreate it
+		c.Client.removeDisappeared(ctx, resp, "Cleura OpenStack project resource has been deleted outside terraform")
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error Reading project resource",
+			"Could not read project resource with id: "+state.Id.ValueString()+": "+err.Error(),
+		)
+		return
+	}
+	result := getProjectModel(project)
+	if len(project.DomainId) == 0 {
+		result.DomainId = types.StringValue(domainId)
+	}
+	tflog.Debug(ctx, fmt.Sprintf("projectResponse: %+v", result))
+
+	// Set refreshed state
+	diags = resp.State.Set(ctx, &result)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (c *openstackProjectResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan openstackProjectResourceModel
+	var currentState openstackProjectResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(req.State.Get(ctx, &currentState)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	err := c.Client.UpdateProject(ctx, c.Client.domainFor(currentState.DomainId), currentState.Id.ValueString(), getProjectJson(plan))
+	if err != nil {
+		if addCancelledError(ctx, &resp.Diagnostics, "Updating project "+currentState.Id.ValueString(), err) {
+			return
+		}
+		resp.Diagnostics.AddError("Failed to update project", err.Error())
+		return
+	}
+	diags := resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (c *openstackProjectResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state openstackProjectResourceModel
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	err := c.Client.DeleteProject(ctx, c.Client.domainFor(state.DomainId), state.Id.ValueString())
+	if err != nil {
+		if addCancelledError(ctx, &resp.Diagnostics, "Deleting project "+state.Id.ValueString(), err) {
+			return
+		}
+		resp.Diagnostics.AddError(
+			"Error Deleting Cleura OpenStack project",
+			"Could not delete Cleura OpenStack project, unexpected error: "+err.Error(),
+		)
+		return
+	}
+}
+
+// ImportState accepts <project_id> or <domain_id>/<project_id>. Without a domain the domain_id of
+// the provider is used.
+func (c *openstackProjectResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	domainId, projectId, found := strings.Cut(req.ID, "/")
+	if !found {
+		domainId, projectId = c.Client.DomainId, req.ID
+	}
+	if domainId == "" || projectId == "" {
+		resp.Diagnostics.AddError(
+			"Invalid import ID",
+			fmt.Sprintf("Expected <project_id> or <domain_id>/<project_id>, got: %q", req.ID),
+		)
+		return
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), projectId)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("domain_id"), domainId)...)
+}
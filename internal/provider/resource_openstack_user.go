@@ -7,7 +7,10 @@ import (
 	"context"
 	"fmt"
 	"slices"
+	"strings"
 
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
@@ -15,12 +18,17 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	tftype "github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/sethvargo/go-password/password"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &cleuraUserResource{}
 var _ resource.ResourceWithImportState = &cleuraUserResource{}
+var _ resource.ResourceWithModifyPlan = &cleuraUserResource{}
+var _ resource.ResourceWithValidateConfig = &cleuraUserResource{}
+var _ resource.ResourceWithUpgradeState = &cleuraUserResource{}
 
 func NewOpenstackUserResource() resource.Resource {
 	return &cleuraUserResource{}
@@ -37,6 +45,7 @@ func (c *cleuraUserResource) Metadata(ctx context.Context, req resource.Metadata
 func (c *cleuraUserResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		Description: "Creates a user in Cleura Cloud",
+		Version:     1,
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
 				Computed: true,
@@ -49,7 +58,13 @@ func (c *cleuraUserResource) Schema(ctx context.Context, req resource.SchemaRequ
 				Required: true,
 			},
 			"domain_id": schema.StringAttribute{
-				Required: true,
+				Description: "Domain the user is created in. Defaults to the domain_id of the provider. Changing it creates a new user.",
+				Optional:    true,
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
 			"default_project_id": schema.StringAttribute{
 				Optional: true,
@@ -60,8 +75,23 @@ func (c *cleuraUserResource) Schema(ctx context.Context, req resource.SchemaRequ
 			"description": schema.StringAttribute{
 				Optional: true,
 			},
-			"projects": schema.ListNestedAttribute{
-				Required: true,
+			"password": schema.StringAttribute{
+				Description: "Password of the user. Generated when not set, and regenerated when password_rotation_keepers changes.",
+				Optional:    true,
+				Computed:    true,
+				Sensitive:   true,
+				PlanModifiers: []planmodifier.String{
+					passwordRotationModifier{},
+				},
+			},
+			"password_rotation_keepers": schema.MapAttribute{
+				Description: "Arbitrary values that trigger a new generated password when changed.",
+				Optional:    true,
+				ElementType: tftype.StringType,
+			},
+			"projects": schema.SetNestedAttribute{
+				Description: "Projects the user is a member of, identified by id.",
+				Required:    true,
 				NestedObject: schema.NestedAttributeObject{
 					Attributes: map[string]schema.Attribute{
 						"id": schema.StringAttribute{
@@ -91,6 +121,14 @@ func (c *cleuraUserResource) Schema(ctx context.Context, req resource.SchemaRequ
 				},
 			},
 		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Read:   true,
+				Update: true,
+				Delete: true,
+			}),
+		},
 	}
 }
 
@@ -109,23 +147,301 @@ func (c *cleuraUserResource) Configure(ctx context.Context, req resource.Configu
 	c.Client = client
 }
 
+// openstackUserPlanProject is used to read projects from a plan where some values may still be unknown.
+type openstackUserPlanProject struct {
+	Id    types.String `tfsdk:"id"`
+	Roles types.Set    `tfsdk:"roles"`
+}
+
+// ModifyPlan validates the planned projects and roles against the domain. This can not be done in
+// ValidateConfig since the provider, and therefore the client, is not configured at that point.
+func (c *cleuraUserResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to validate on destroy or before the provider is configured
+	if req.Plan.Raw.IsNull() || c.Client == nil {
+		return
+	}
+	var planProjects, stateProjects types.Set
+	var planDomainId, configDomainId types.String
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("projects"), &planProjects)...)
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("domain_id"), &planDomainId)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("domain_id"), &configDomainId)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if !req.State.Raw.IsNull() {
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("projects"), &stateProjects)...)
+		// Only validate what is about to change
+		if planProjects.Equal(stateProjects) {
+			return
+		}
+	}
+	// The domain is only known at apply when it comes from another resource
+	if planProjects.IsUnknown() || planProjects.IsNull() || configDomainId.IsUnknown() {
+		return
+	}
+	domainId := c.Client.domainFor(planDomainId)
+	availableRoles, err := c.Client.CachedRoles(ctx, domainId)
+	if err != nil {
+		if addCancelledError(ctx, &resp.Diagnostics, "Validating roles", err) {
+			return
+		}
+		resp.Diagnostics.AddWarning("Unable to validate roles", "Could not list the roles of the domain, roles will be validated on apply: "+err.Error())
+		return
+	}
+	availableProjects, err := c.Client.CachedProjects(ctx, domainId, false)
+	if err == nil && !containsProjects(availableProjects, planProjects) {
+		// The project may have been created outside the provider since the projects were listed
+		availableProjects, err = c.Client.CachedProjects(ctx, domainId, true)
+	}
+	if err != nil {
+		if addCancelledError(ctx, &resp.Diagnostics, "Validating projects", err) {
+			return
+		}
+		resp.Diagnostics.AddWarning("Unable to validate projects", "Could not list the projects of the domain, projects will be validated on apply: "+err.Error())
+		return
+	}
+	var roleNames []string
+	for _, r := range availableRoles {
+		roleNames = append(roleNames, r.Name)
+	}
+	var projectIds []string
+	for _, p := range availableProjects {
+		projectIds = append(projectIds, p.Id)
+	}
+
+	for _, element := range planProjects.Elements() {
+		projectPath := path.Root("projects").AtSetValue(element)
+		var p openstackUserPlanProject
+		obj, ok := element.(types.Object)
+		if !ok || obj.IsUnknown() {
+			continue
+		}
+		resp.Diagnostics.Append(obj.As(ctx, &p, basetypes.ObjectAsOptions{})...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		if !p.Id.IsUnknown() && !slices.Contains(projectIds, p.Id.ValueString()) {
+			resp.Diagnostics.AddAttributeError(
+				projectPath.AtName("id"),
+				"Unknown project",
+				fmt.Sprintf("Project %q does not exist in domain %s.", p.Id.ValueString(), domainId),
+			)
+		}
+		if p.Roles.IsUnknown() {
+			continue
+		}
+		for _, r := range p.Roles.Elements() {
+			role, ok := r.(types.String)
+			if !ok || role.IsUnknown() {
+				continue
+			}
+			if !slices.Contains(roleNames, role.ValueString()) {
+				resp.Diagnostics.AddAttributeError(
+					projectPath.AtName("roles").AtSetValue(role),
+					"Unknown role",
+					fmt.Sprintf("Role %q does not exist in domain %s. Valid roles are: %s", role.ValueString(), domainId, strings.Join(roleNames, ", ")),
+				)
+			}
+		}
+	}
+}
+
+// containsProjects reports whether every known project id in planned is one of available.
+func containsProjects(available []openstackProjectResourceJson, planned types.Set) bool {
+	for _, element := range planned.Elements() {
+		obj, ok := element.(types.Object)
+		if !ok || obj.IsUnknown() {
+			continue
+		}
+		id, ok := obj.Attributes()["id"].(types.String)
+		if !ok || id.IsUnknown() {
+			continue
+		}
+		if !slices.ContainsFunc(available, func(p openstackProjectResourceJson) bool { return p.Id == id.ValueString() }) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateConfig makes sure every project is only listed once, since projects are identified by id.
+func (c *cleuraUserResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var projects types.Set
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("projects"), &projects)...)
+	if resp.Diagnostics.HasError() || projects.IsUnknown() || projects.IsNull() {
+		return
+	}
+	seen := map[string]bool{}
+	for _, element := range projects.Elements() {
+		var p openstackUserPlanProject
+		obj, ok := element.(types.Object)
+		if !ok || obj.IsUnknown() {
+			continue
+		}
+		resp.Diagnostics.Append(obj.As(ctx, &p, basetypes.ObjectAsOptions{})...)
+		if p.Id.IsUnknown() || p.Id.IsNull() {
+			continue
+		}
+		if seen[p.Id.ValueString()] {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("projects").AtSetValue(element).AtName("id"),
+				"Duplicate project",
+				fmt.Sprintf("Project %q is listed more than once, all roles for a project must be given in a single block.", p.Id.ValueString()),
+			)
+		}
+		seen[p.Id.ValueString()] = true
+	}
+}
+
+// openstackUserResourceModelV0 is the state of version 0, before projects became a set and the
+// password was managed.
+type openstackUserResourceModelV0 struct {
+	Id               types.String                 `tfsdk:"id"`
+	Name             types.String                 `tfsdk:"name"`
+	DomainId         types.String                 `tfsdk:"domain_id"`
+	DefaultProjectId types.String                 `tfsdk:"default_project_id"`
+	Enabled          types.Bool                   `tfsdk:"enabled"`
+	Description      types.String                 `tfsdk:"description"`
+	Projects         []openstackUserCreateProject `tfsdk:"projects"`
+}
+
+// openstackUserSchemaV0 is the schema of version 0. It is fixed, later changes to the current schema
+// must not change how old state is read.
+func openstackUserSchemaV0() schema.Schema {
+	return schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"name": schema.StringAttribute{
+				Required: true,
+			},
+			"domain_id": schema.StringAttribute{
+				Required: true,
+			},
+			"default_project_id": schema.StringAttribute{
+				Optional: true,
+			},
+			"enabled": schema.BoolAttribute{
+				Required: true,
+			},
+			"description": schema.StringAttribute{
+				Optional: true,
+			},
+			"projects": schema.ListNestedAttribute{
+				Required: true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							Required: true,
+						},
+						"roles": schema.SetAttribute{
+							Required:    true,
+							ElementType: tftype.StringType,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// UpgradeState migrates state from version 0, where projects was a list, to the set used since version 1.
+func (c *cleuraUserResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	priorSchema := openstackUserSchemaV0()
+	return map[int64]resource.StateUpgrader{
+		0: {
+			PriorSchema: &priorSchema,
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				var prior openstackUserResourceModelV0
+				resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+				if resp.Diagnostics.HasError() {
+					return
+				}
+				// Version 0 did not manage the password, it stays unmanaged until it is configured or rotated
+				upgraded := openstackUserResourceModel{
+					Id:               prior.Id,
+					Name:             prior.Name,
+					DomainId:         prior.DomainId,
+					DefaultProjectId: prior.DefaultProjectId,
+					Enabled:          prior.Enabled,
+					Description:      prior.Description,
+					Projects:         prior.Projects,
+					Password:         types.StringNull(),
+					PasswordKeepers:  types.MapNull(tftype.StringType),
+					Timeouts: timeouts.Value{Object: types.ObjectNull(map[string]attr.Type{
+						"create": tftype.StringType,
+						"read":   tftype.StringType,
+						"update": tftype.StringType,
+						"delete": tftype.StringType,
+					})},
+				}
+				resp.Diagnostics.Append(resp.State.Set(ctx, upgraded)...)
+			},
+		},
+	}
+}
+
 func (c *cleuraUserResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
 	var plan openstackUserResourceModel
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
+	createTimeout, diags := plan.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := withOperationTimeout(ctx, createTimeout)
+	defer cancel()
+
+	if plan.Password.IsUnknown() || plan.Password.IsNull() {
+		pw, err := generateUserPassword()
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to generate password", err.Error())
+			return
+		}
+		plan.Password = types.StringValue(pw)
+	}
+	plan.DomainId = types.StringValue(c.Client.domainFor(plan.DomainId))
 
 	result, err := c.Client.CreateUser(ctx, plan)
 	if err != nil {
+		if addCancelledError(ctx, &resp.Diagnostics, "Creating user "+plan.Name.ValueString(), err) {
+			return
+		}
 		tflog.Error(ctx, fmt.Sprintf("failed to create user, error: %s", err.Error()))
+		if IsConflict(err) {
+			resp.Diagnostics.AddError("User already exists", fmt.Sprintf("user %s already exists in domain %s, import it instead. error: %s", plan.Name.ValueString(), plan.DomainId.ValueString(), err.Error()))
+			return
+		}
 		resp.Diagnostics.AddError("Failed to create user", fmt.Sprintf("error: %s", err.Error()))
 		return
 	}
 	plan.Id = types.StringValue(result.Id)
 	tflog.Trace(ctx, "created user resource")
 
+	// Users are always created enabled
+	if !plan.Enabled.ValueBool() {
+		if err := c.Client.ToggleUserEnabled(ctx, plan.DomainId.ValueString(), result.Id, false); err != nil {
+			created := plan
+			created.Enabled = types.BoolValue(true)
+			resp.Diagnostics.Append(resp.State.Set(ctx, &created)...)
+			if addCancelledError(ctx, &resp.Diagnostics, "Creating user "+plan.Name.ValueString(), err) {
+				return
+			}
+			resp.Diagnostics.AddError("Failed to disable user", fmt.Sprintf("user %s was created enabled, error: %s", plan.Name.ValueString(), err.Error()))
+			return
+		}
+	}
+
+	err = c.waitForUser(ctx, plan)
+	// The user exists, so it is written to state even if it has not become consistent
 	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+	if err != nil {
+		addConsistencyError(ctx, &resp.Diagnostics, "Creating user "+plan.Name.ValueString(), err)
+	}
 }
 
 func (c *cleuraUserResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
@@ -135,18 +451,23 @@ func (c *cleuraUserResource) Read(ctx context.Context, req resource.ReadRequest,
 	if resp.Diagnostics.HasError() {
 		return
 	}
-	exist, err := c.Client.DoesUserExist(ctx, state.Id.ValueString())
-	if err != nil {
-		resp.Diagnostics.AddError("Failed to check if user already exists", err.Error())
+	readTimeout, diags := state.Timeouts.Read(ctx, defaultReadTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
-	if !exist {
+	ctx, cancel := withOperationTimeout(ctx, readTimeout)
+	defer cancel()
+	userResponse, err := c.Client.GetUserResource(ctx, state.DomainId.ValueString(), state.Id.ValueString())
+	if IsNotFound(err) {
 		// The user has been removed from outside Terraform, recreate it
-		resp.State.RemoveResource(ctx)
-		resp.Diagnostics.AddWarning("Cleura User resource has been deleted outside terraform", "New resource will be created")
+		c.Client.removeDisappeared(ctx, resp, "Cleura User resource has been deleted outside terraform")
 		return
 	}
-	userResponse, err := c.Client.GetUserResource(ctx, state.Id.ValueString())
 	if err != nil {
+		if addCancelledError(ctx, &resp.Diagnostics, "Reading user "+state.Id.ValueString(), err) {
+			return
+		}
 		resp.Diagnostics.AddError(
 			"Error Reading user resource",
 			"Could not read user resource named: "+state.Name.ValueString()+": "+err.Error(),
@@ -154,6 +475,9 @@ func (c *cleuraUserResource) Read(ctx context.Context, req resource.ReadRequest,
 		return
 	}
 	tflog.Debug(ctx, fmt.Sprintf("userResponse: %+v", userResponse))
+	userResponse.Password = state.Password
+	userResponse.PasswordKeepers = state.PasswordKeepers
+	userResponse.Timeouts = state.Timeouts
 
 	// Set refreshed state
 	diags = resp.State.Set(ctx, &userResponse)
@@ -175,58 +499,71 @@ func (c *cleuraUserResource) Update(ctx context.Context, req resource.UpdateRequ
 		return
 	}
 
+	updateTimeout, diags := plan.Timeouts.Update(ctx, defaultUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := withOperationTimeout(ctx, updateTimeout)
+	defer cancel()
+
+	// updated holds what has been applied so far, an interrupted update records the steps that succeeded
+	updated := currentState
+	updated.Timeouts = plan.Timeouts
+	fail := func(summary string, err error) {
+		resp.Diagnostics.Append(resp.State.Set(ctx, updated)...)
+		if addCancelledError(ctx, &resp.Diagnostics, "Updating user "+currentState.Id.ValueString(), err) {
+			return
+		}
+		resp.Diagnostics.AddError(summary, err.Error())
+	}
+
 	if currentState.Enabled != plan.Enabled {
-		err := c.Client.ToggleUserEnabled(ctx, currentState.Id.ValueString(), plan.Enabled.ValueBool())
+		err := c.Client.ToggleUserEnabled(ctx, currentState.DomainId.ValueString(), currentState.Id.ValueString(), plan.Enabled.ValueBool())
 		if err != nil {
-			resp.Diagnostics.AddError("Failed to update user", err.Error())
-			return
-		}
-	}
-	currentProjects := make([]openstackUserCreateProject, 0)
-	currentProjects = append(currentProjects, currentState.Projects...)
-	for _, p := range plan.Projects {
-		// Check if the planned project is in the current state
-		for _, st := range currentProjects {
-			if st.Id == p.Id {
-				// This planned project is in the current state
-				// Compare what roles are to be ADDED
-				for _, r := range p.Roles {
-					if !slices.Contains(st.Roles, r) {
-						// The planned role is not in the current state, therefore we must add it
-						err := c.Client.AddUserToProjectRole(ctx, currentState.Id.ValueString(), p.Id, r)
-						if err != nil {
-							resp.Diagnostics.AddError("Failed to add user to project role", err.Error())
-							return
-						}
-					}
-				}
-				// Compare what roles are to be DELETED
-				for _, r := range st.Roles {
-					if !slices.Contains(p.Roles, r) {
-						// A role in the state has been removed from the plan, therefore we must REMOVE it
-						err := c.Client.RemoveUserFromProjectRole(ctx, currentState.Id.ValueString(), p.Id, r)
-						if err != nil {
-							resp.Diagnostics.AddError("Failed to remove user from project role", err.Error())
-							return
-						}
-					}
-				}
-			} else {
-				// This planned project is NOT in the current state
-				// We must add the user to it along with the specified roles
-				projAssign := openstackProjectAssignment{ProjectId: p.Id, Roles: p.Roles}
-				projSlice := []openstackProjectAssignment{projAssign}
-				proj := openstackProjectUpdate{Projects: projSlice}
-				c.Client.AddUserToProject(proj)
-			}
+			fail("Failed to update user", err)
+			return
 		}
+		updated.Enabled = plan.Enabled
 	}
-	diags := resp.State.Set(ctx, plan)
-	resp.Diagnostics.Append(diags...)
-	if resp.Diagnostics.HasError() {
+	if !currentState.Description.Equal(plan.Description) {
+		err := c.Client.SetUserDescription(ctx, currentState.DomainId.ValueString(), currentState.Id.ValueString(), plan.Description.ValueString())
+		if err != nil {
+			fail("Failed to update user", err)
+			return
+		}
+		updated.Description = plan.Description
+	}
+	if plan.Password.IsUnknown() {
+		pw, err := generateUserPassword()
+		if err != nil {
+			fail("Failed to generate password", err)
+			return
+		}
+		plan.Password = types.StringValue(pw)
+	}
+	if !plan.Password.Equal(currentState.Password) {
+		err := c.Client.SetUserPassword(ctx, currentState.DomainId.ValueString(), currentState.Id.ValueString(), plan.Password.ValueString())
+		if err != nil {
+			fail("Failed to update user password", err)
+			return
+		}
+	}
+	updated.Password = plan.Password
+	updated.PasswordKeepers = plan.PasswordKeepers
+	applied, err := c.Client.applyProjectMembership(ctx, currentState.DomainId.ValueString(), currentState.Id.ValueString(), newProjectMembership(currentState.Projects), newProjectMembership(plan.Projects))
+	if err != nil {
+		// Keep the memberships that were changed before the failure in state
+		updated.Projects = applied.projects()
+		fail("Failed to update project memberships", err)
 		return
 	}
-
+	err = c.waitForUser(ctx, plan)
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+	if err != nil {
+		addConsistencyError(ctx, &resp.Diagnostics, "Updating user "+currentState.Id.ValueString(), err)
+	}
 }
 
 func (c *cleuraUserResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
@@ -236,8 +573,18 @@ func (c *cleuraUserResource) Delete(ctx context.Context, req resource.DeleteRequ
 	if resp.Diagnostics.HasError() {
 		return
 	}
-	err := c.Client.DeleteUser(ctx, state.Id.ValueString())
+	deleteTimeout, diags := state.Timeouts.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := withOperationTimeout(ctx, deleteTimeout)
+	defer cancel()
+	err := c.Client.DeleteUser(ctx, state.DomainId.ValueString(), state.Id.ValueString())
 	if err != nil {
+		if addCancelledError(ctx, &resp.Diagnostics, "Deleting user "+state.Id.ValueString(), err) {
+			return
+		}
 		resp.Diagnostics.AddError(
 			"Error Deleting Cleura user",
 			"Could not delete Cleura user, unexpected error: "+err.Error(),
@@ -246,6 +593,101 @@ func (c *cleuraUserResource) Delete(ctx context.Context, req resource.DeleteRequ
 	}
 }
 
+// ImportState accepts <user_id>, <domain_id>/<user_id> or <domain_id>/name:<username>. Without a
+// domain the domain_id of the provider is used.
 func (c *cleuraUserResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+	domainId, user, found := strings.Cut(req.ID, "/")
+	if !found {
+		domainId, user = c.Client.DomainId, req.ID
+	}
+	if domainId == "" || user == "" {
+		resp.Diagnostics.AddError(
+			"Invalid import ID",
+			fmt.Sprintf("Expected <user_id>, <domain_id>/<user_id> or <domain_id>/name:<username>, got: %q", req.ID),
+		)
+		return
+	}
+	if name, byName := strings.CutPrefix(user, "name:"); byName {
+		id, ok, err := c.Client.FindUserByName(ctx, domainId, name)
+		if err != nil {
+			if addCancelledError(ctx, &resp.Diagnostics, "Importing user "+req.ID, err) {
+				return
+			}
+			resp.Diagnostics.AddError("Failed to look up user by name", err.Error())
+			return
+		}
+		if !ok {
+			resp.Diagnostics.AddError("User not found", fmt.Sprintf("No user named %q exists in domain %s", name, domainId))
+			return
+		}
+		user = id
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), user)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("domain_id"), domainId)...)
+}
+
+// waitForUser polls the user until the API returns the enabled flag and project roles of plan.
+func (c *cleuraUserResource) waitForUser(ctx context.Context, plan openstackUserResourceModel) error {
+	return waitForConsistency(ctx, func(ctx context.Context) ([]string, error) {
+		observed, err := c.Client.GetUserResource(ctx, plan.DomainId.ValueString(), plan.Id.ValueString())
+		if IsNotFound(err) {
+			return []string{"user is not visible yet"}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return userDifferences(plan, observed), nil
+	})
+}
+
+// userDifferences lists how the user read from the API differs from plan.
+func userDifferences(plan, observed openstackUserResourceModel) []string {
+	var differences []string
+	if !observed.Enabled.Equal(plan.Enabled) {
+		differences = append(differences, fmt.Sprintf("enabled is %s, expected %s", observed.Enabled, plan.Enabled))
+	}
+	for _, op := range diffProjectMembership(newProjectMembership(observed.Projects), newProjectMembership(plan.Projects)) {
+		switch op.Kind {
+		case addProject, addRole:
+			differences = append(differences, fmt.Sprintf("project %s is missing roles %s", op.ProjectId, strings.Join(op.Roles, ", ")))
+		case removeRole, removeProject:
+			differences = append(differences, fmt.Sprintf("project %s still has roles %s", op.ProjectId, strings.Join(op.Roles, ", ")))
+		}
+	}
+	return differences
+}
+
+// generateUserPassword returns a random password accepted by the Cleura password policy.
+func generateUserPassword() (string, error) {
+	return password.Generate(12, 2, 0, false, true)
+}
+
+// passwordRotationModifier keeps the password from state unless it is set in the
+// configuration or password_rotation_keepers has changed, in which case a new one is generated.
+type passwordRotationModifier struct{}
+
+func (m passwordRotationModifier) Description(_ context.Context) string {
+	return "Keeps the generated password unless password_rotation_keepers changes."
+}
+
+func (m passwordRotationModifier) MarkdownDescription(ctx context.Context) string {
+	return m.Description(ctx)
+}
+
+func (m passwordRotationModifier) PlanModifyString(ctx context.Context, req planmodifier.StringRequest, resp *planmodifier.StringResponse) {
+	// Nothing to keep on create, and a configured password always wins
+	if req.State.Raw.IsNull() || !req.ConfigValue.IsNull() {
+		return
+	}
+	var planKeepers, stateKeepers types.Map
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("password_rotation_keepers"), &planKeepers)...)
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("password_rotation_keepers"), &stateKeepers)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if !planKeepers.Equal(stateKeepers) {
+		resp.PlanValue = types.StringUnknown()
+		return
+	}
+	resp.PlanValue = req.StateValue
 }
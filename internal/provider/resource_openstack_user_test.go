@@ -0,0 +1,242 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func testUser(enabled bool, password string, projects map[string][]string) openstackUserResourceModel {
+	return openstackUserResourceModel{
+		Id:               types.StringValue("u1"),
+		Name:             types.StringValue("alice"),
+		DomainId:         types.StringValue("d"),
+		DefaultProjectId: types.StringNull(),
+		Enabled:          types.BoolValue(enabled),
+		Description:      types.StringNull(),
+		Projects:         membership(projects).projects(),
+		Password:         types.StringValue(password),
+		PasswordKeepers:  types.MapNull(types.StringType),
+		Timeouts:         nullTimeouts(),
+	}
+}
+
+// TestUpdateRecordsAppliedSteps interrupts an update after each of its steps and checks that
+// state holds exactly the steps that succeeded.
+func TestUpdateRecordsAppliedSteps(t *testing.T) {
+	current := testUser(true, "Old-Password-1", map[string][]string{"p1": {"member"}})
+	planned := testUser(false, "New-Password-1", map[string][]string{"p2": {"member"}, "p3": {"member"}})
+	tests := []struct {
+		name      string
+		responses int32
+		want      openstackUserResourceModel
+	}{
+		{"after enabling", 1, testUser(false, "Old-Password-1", map[string][]string{"p1": {"member"}})},
+		{"after the password", 2, testUser(false, "New-Password-1", map[string][]string{"p1": {"member"}})},
+		{"after the first project", 3, testUser(false, "New-Password-1", map[string][]string{"p1": {"member"}, "p2": {"member"}})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeAPI()
+			api.seedUser("d", "u1", "alice", map[string][]string{"p1": {"member"}})
+			client := newFakeClient(t, api)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			client.Client.Transport = cancelAfterResponses(client.Client.Transport, cancel, tt.responses)
+			h := newResourceHarness(t, NewOpenstackUserResource(), client)
+			h.ctx = ctx
+
+			state, err := h.Update(h.state(current), planned)
+			if err == nil || !strings.Contains(err.Error(), "Operation cancelled") {
+				t.Fatalf("expected the update to be cancelled, got: %v", err)
+			}
+			var got openstackUserResourceModel
+			h.get(state, &got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("state is %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateFailureRecordsAppliedSteps(t *testing.T) {
+	api := newFakeAPI()
+	api.seedUser("d", "u1", "alice", map[string][]string{"p1": {"member"}})
+	api.fail["POST /accesscontrol/v1/openstack/d/users/u1/projects"] = http.StatusInternalServerError
+	h := newResourceHarness(t, NewOpenstackUserResource(), newFakeClient(t, api))
+
+	current := testUser(true, "Old-Password-1", map[string][]string{"p1": {"member"}})
+	planned := testUser(false, "New-Password-1", map[string][]string{"p2": {"member"}})
+	state, err := h.Update(h.state(current), planned)
+	if err == nil || !strings.Contains(err.Error(), "Failed to update project memberships") {
+		t.Fatalf("expected the membership update to fail, got: %v", err)
+	}
+	var got openstackUserResourceModel
+	h.get(state, &got)
+	if want := testUser(false, "New-Password-1", map[string][]string{"p1": {"member"}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("state is %+v, want %+v", got, want)
+	}
+}
+
+func TestUpgradeStateFromV0(t *testing.T) {
+	ctx := context.Background()
+	r := NewOpenstackUserResource().(*cleuraUserResource)
+	upgrader := r.UpgradeState(ctx)[0]
+	prior := tfsdk.State{Schema: *upgrader.PriorSchema, Raw: tftypes.NewValue(upgrader.PriorSchema.Type().TerraformType(ctx), nil)}
+	if diags := prior.Set(ctx, openstackUserResourceModelV0{
+		Id:               types.StringValue("u1"),
+		Name:             types.StringValue("alice"),
+		DomainId:         types.StringValue("d"),
+		DefaultProjectId: types.StringNull(),
+		Enabled:          types.BoolValue(true),
+		Description:      types.StringValue("Alice"),
+		Projects:         membership(map[string][]string{"p1": {"member"}, "p2": {"admin", "member"}}).projects(),
+	}); diags.HasError() {
+		t.Fatalf("set prior state: %v", diags)
+	}
+
+	h := newResourceHarness(t, r, nil)
+	resp := resource.UpgradeStateResponse{State: h.emptyState()}
+	upgrader.StateUpgrader(ctx, resource.UpgradeStateRequest{State: &prior}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("upgrade: %v", resp.Diagnostics)
+	}
+	var got openstackUserResourceModel
+	h.get(resp.State, &got)
+	want := testUser(true, "", map[string][]string{"p1": {"member"}, "p2": {"admin", "member"}})
+	want.Description = types.StringValue("Alice")
+	want.Password = types.StringNull()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("upgraded state is %+v, want %+v", got, want)
+	}
+}
+
+func TestCreateDisabledUser(t *testing.T) {
+	api := newFakeAPI()
+	h := newResourceHarness(t, NewOpenstackUserResource(), newFakeClient(t, api))
+	// The user must become consistent long before the default create timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	h.ctx = ctx
+
+	planned := testUser(false, "Secret-Password-1", map[string][]string{"p1": {"member"}})
+	planned.Id = types.StringUnknown()
+	state, err := h.Create(planned)
+	if err != nil {
+		t.Fatalf("create: %s", err)
+	}
+	var created openstackUserResourceModel
+	h.get(state, &created)
+	if created.Enabled.ValueBool() {
+		t.Error("state records the user as enabled")
+	}
+	if user := api.users["d/"+created.Id.ValueString()]; user.Enabled {
+		t.Error("the user was created enabled in Cleura")
+	}
+}
+
+func withKeepers(user openstackUserResourceModel, keeper string) openstackUserResourceModel {
+	user.PasswordKeepers = types.MapValueMust(types.StringType, map[string]attr.Value{"rotated": types.StringValue(keeper)})
+	return user
+}
+
+// TestPasswordRotationModifier checks the planned password: kept from state while the keepers are
+// unchanged, unknown so that a new one is generated when they change, and as configured if set.
+func TestPasswordRotationModifier(t *testing.T) {
+	current := withKeepers(testUser(true, "Old-Password-1", nil), "1")
+	tests := []struct {
+		name   string
+		config openstackUserResourceModel
+		want   types.String
+	}{
+		{"unchanged keepers", withKeepers(testUser(true, "", nil), "1"), types.StringValue("Old-Password-1")},
+		{"changed keepers", withKeepers(testUser(true, "", nil), "2"), types.StringUnknown()},
+		{"configured password", withKeepers(testUser(true, "Set-Password-1", nil), "2"), types.StringValue("Set-Password-1")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newResourceHarness(t, NewOpenstackUserResource(), nil)
+			config := tt.config
+			planned := tt.config
+			if tt.config.Password.ValueString() == "" {
+				config.Password = types.StringNull()
+				planned.Password = types.StringUnknown()
+			}
+			configState, planState, state := h.state(config), h.state(planned), h.state(current)
+			req := planmodifier.StringRequest{
+				Path:        path.Root("password"),
+				Config:      tfsdk.Config{Schema: configState.Schema, Raw: configState.Raw},
+				ConfigValue: config.Password,
+				Plan:        tfsdk.Plan{Schema: planState.Schema, Raw: planState.Raw},
+				PlanValue:   planned.Password,
+				State:       state,
+				StateValue:  current.Password,
+			}
+			resp := planmodifier.StringResponse{PlanValue: req.PlanValue}
+			passwordRotationModifier{}.PlanModifyString(h.ctx, req, &resp)
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("modify plan: %v", resp.Diagnostics)
+			}
+			if !resp.PlanValue.Equal(tt.want) {
+				t.Errorf("planned password %s, want %s", resp.PlanValue, tt.want)
+			}
+		})
+	}
+}
+
+// TestUpdatePassword checks that an update sets the password only when the plan changes it, and
+// generates one when the plan leaves it unknown.
+func TestUpdatePassword(t *testing.T) {
+	current := withKeepers(testUser(true, "Old-Password-1", nil), "1")
+	tests := []struct {
+		name     string
+		password types.String
+		keeper   string
+		want     string
+	}{
+		{"kept password", types.StringValue("Old-Password-1"), "1", "Old-Password-1"},
+		{"rotated password", types.StringUnknown(), "2", ""},
+		{"configured password", types.StringValue("Set-Password-1"), "2", "Set-Password-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeAPI()
+			api.seedUser("d", "u1", "alice", nil)
+			api.passwords["d/u1"] = "Old-Password-1"
+			h := newResourceHarness(t, NewOpenstackUserResource(), newFakeClient(t, api))
+
+			planned := withKeepers(testUser(true, "", nil), tt.keeper)
+			planned.Password = tt.password
+			state, err := h.Update(h.state(current), planned)
+			if err != nil {
+				t.Fatalf("update: %s", err)
+			}
+			var updated openstackUserResourceModel
+			h.get(state, &updated)
+			password := updated.Password.ValueString()
+			switch {
+			case tt.want != "" && password != tt.want:
+				t.Errorf("password in state is %q, want %q", password, tt.want)
+			case tt.want == "" && (password == "" || password == "Old-Password-1"):
+				t.Errorf("expected a new password in state, got %q", password)
+			}
+			if got := api.passwords["d/u1"]; got != password {
+				t.Errorf("password in Cleura is %q, state has %q", got, password)
+			}
+			if sets := countRequests(api.Requests(), "PUT /accesscontrol/v1/openstack/d/users/u1"); (sets == 0) != (password == "Old-Password-1") {
+				t.Errorf("sent %d updates for password %q", sets, password)
+			}
+		})
+	}
+}
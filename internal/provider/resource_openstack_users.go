@@ -0,0 +1,454 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &openstackUsersResource{}
+
+// ==============
+// RESOURCE MODEL
+// ==============
+type openstackUsersResourceModel struct {
+	Id             types.String                  `tfsdk:"id"`
+	DomainId       types.String                  `tfsdk:"domain_id"`
+	MaxConcurrency types.Int64                   `tfsdk:"max_concurrency"`
+	Users          map[string]openstackUsersUser `tfsdk:"users"`
+}
+type openstackUsersUser struct {
+	Id          types.String                 `tfsdk:"id"`
+	Description types.String                 `tfsdk:"description"`
+	Enabled     types.Bool                   `tfsdk:"enabled"`
+	Password    types.String                 `tfsdk:"password"`
+	Projects    []openstackUserCreateProject `tfsdk:"projects"`
+}
+
+// toUserModel converts a map entry into the model used by the single user resource so the client can be shared.
+func (u openstackUsersUser) toUserModel(domainId types.String, name string) openstackUserResourceModel {
+	return openstackUserResourceModel{
+		Id:               u.Id,
+		Name:             types.StringValue(name),
+		DomainId:         domainId,
+		DefaultProjectId: types.StringNull(),
+		Enabled:          u.Enabled,
+		Description:      u.Description,
+		Projects:         u.Projects,
+		Password:         u.Password,
+		PasswordKeepers:  types.MapNull(types.StringType),
+	}
+}
+
+func NewOpenstackUsersResource() resource.Resource {
+	return &openstackUsersResource{}
+}
+
+type openstackUsersResource struct {
+	Client *CleuraClient
+}
+
+func (c *openstackUsersResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_openstack_users"
+}
+
+func (c *openstackUsersResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Manages a set of users in Cleura Cloud, keyed by username",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"domain_id": schema.StringAttribute{
+				Description: "Domain the users are created in. Defaults to the domain_id of the provider. Changing it creates new users.",
+				Optional:    true,
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"max_concurrency": schema.Int64Attribute{
+				Description: "Number of users that are reconciled in parallel. Defaults to 4.",
+				Optional:    true,
+				Computed:    true,
+				Default:     int64default.StaticInt64(4),
+			},
+			"users": schema.MapNestedAttribute{
+				Description: "Users to manage, keyed by username. Users that fail to be created are reported as warnings and created by the next apply, unless no user of a new resource could be created.",
+				Required:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							Computed: true,
+							PlanModifiers: []planmodifier.String{
+								stringplanmodifier.UseStateForUnknown(),
+							},
+						},
+						"description": schema.StringAttribute{
+							Optional: true,
+						},
+						"enabled": schema.BoolAttribute{
+							Description: "Defaults to true.",
+							Optional:    true,
+							Computed:    true,
+							Default:     booldefault.StaticBool(true),
+						},
+						"password": schema.StringAttribute{
+							Description: "Generated password of the user.",
+							Computed:    true,
+							Sensitive:   true,
+							PlanModifiers: []planmodifier.String{
+								stringplanmodifier.UseStateForUnknown(),
+							},
+						},
+						"projects": schema.SetNestedAttribute{
+							Required: true,
+							NestedObject: schema.NestedAttributeObject{
+								Attributes: map[string]schema.Attribute{
+									"id": schema.StringAttribute{
+										Required: true,
+									},
+									"roles": schema.SetAttribute{
+										Required:    true,
+										ElementType: types.StringType,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (c *openstackUsersResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	client, ok := req.ProviderData.(*CleuraClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unable to cast ProviderData to *CleuraClient",
+			fmt.Sprintf("Expected *CleuraClient, got: %T", req.ProviderData),
+		)
+		return
+	}
+	c.Client = client
+}
+
+// userResult is the outcome of reconciling a single user. A nil user means it no longer exists,
+// notCreated that creating it failed.
+type userResult struct {
+	name       string
+	user       *openstackUsersUser
+	notCreated bool
+	diags      diag.Diagnostics
+}
+
+// forEachUser runs fn for every name with at most limit calls in flight and collects the results.
+// Once ctx is cancelled fn still runs for the remaining names, but every request fails immediately.
+func forEachUser(names []string, limit int64, fn func(name string) userResult) []userResult {
+	if limit < 1 {
+		limit = 1
+	}
+	results := make([]userResult, len(names))
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = fn(name)
+		}(i, name)
+	}
+	wg.Wait()
+	return results
+}
+
+func (c *openstackUsersResource) createUser(ctx context.Context, domainId types.String, name string, user openstackUsersUser) userResult {
+	result := userResult{name: name, notCreated: true}
+	pw, err := generateUserPassword()
+	if err != nil {
+		result.diags.AddError(fmt.Sprintf("Failed to generate password for user %s", name), err.Error())
+		return result
+	}
+	user.Password = types.StringValue(pw)
+	created, err := c.Client.CreateUser(ctx, user.toUserModel(domainId, name))
+	if addCancelledError(ctx, &result.diags, "Creating user "+name, err) {
+		return result
+	}
+	if err != nil {
+		result.diags.AddAttributeError(path.Root("users").AtMapKey(name), fmt.Sprintf("Failed to create user %s", name), err.Error())
+		return result
+	}
+	user.Id = types.StringValue(created.Id)
+	result.user = &user
+	result.notCreated = false
+	// Users are always created enabled
+	if !user.Enabled.ValueBool() {
+		if err := c.Client.ToggleUserEnabled(ctx, domainId.ValueString(), created.Id, false); err != nil {
+			user.Enabled = types.BoolValue(true)
+			if addCancelledError(ctx, &result.diags, "Creating user "+name, err) {
+				return result
+			}
+			result.diags.AddAttributeError(path.Root("users").AtMapKey(name), fmt.Sprintf("Failed to disable user %s", name), fmt.Sprintf("The user was created enabled, error: %s", err.Error()))
+		}
+	}
+	return result
+}
+
+func (c *openstackUsersResource) updateUser(ctx context.Context, domainId types.String, name string, current, planned openstackUsersUser) userResult {
+	result := userResult{name: name, user: &current}
+	id := current.Id.ValueString()
+	if !current.Enabled.Equal(planned.Enabled) {
+		if err := c.Client.ToggleUserEnabled(ctx, domainId.ValueString(), id, planned.Enabled.ValueBool()); err != nil {
+			if addCancelledError(ctx, &result.diags, "Updating user "+name, err) {
+				return result
+			}
+			result.diags.AddAttributeError(path.Root("users").AtMapKey(name), fmt.Sprintf("Failed to update user %s", name), err.Error())
+			return result
+		}
+		current.Enabled = planned.Enabled
+	}
+	if !current.Description.Equal(planned.Description) {
+		if err := c.Client.SetUserDescription(ctx, domainId.ValueString(), id, planned.Description.ValueString()); err != nil {
+			if addCancelledError(ctx, &result.diags, "Updating user "+name, err) {
+				return result
+			}
+			result.diags.AddAttributeError(path.Root("users").AtMapKey(name), fmt.Sprintf("Failed to update user %s", name), err.Error())
+			return result
+		}
+		current.Description = planned.Description
+	}
+	applied, err := c.Client.applyProjectMembership(ctx, domainId.ValueString(), id, newProjectMembership(current.Projects), newProjectMembership(planned.Projects))
+	if err != nil {
+		current.Projects = applied.projects()
+		if addCancelledError(ctx, &result.diags, "Updating user "+name, err) {
+			return result
+		}
+		result.diags.AddAttributeError(path.Root("users").AtMapKey(name), fmt.Sprintf("Failed to update project memberships of user %s", name), err.Error())
+		return result
+	}
+	planned.Id = current.Id
+	planned.Password = current.Password
+	result.user = &planned
+	return result
+}
+
+func (c *openstackUsersResource) deleteUser(ctx context.Context, domainId types.String, name string, current openstackUsersUser) userResult {
+	if current.Id.IsNull() {
+		// The user failed to be created, there is nothing to delete
+		return userResult{name: name}
+	}
+	if err := c.Client.DeleteUser(ctx, domainId.ValueString(), current.Id.ValueString()); err != nil {
+		result := userResult{name: name, user: &current}
+		if addCancelledError(ctx, &result.diags, "Deleting user "+name, err) {
+			return result
+		}
+		result.diags.AddAttributeError(path.Root("users").AtMapKey(name), fmt.Sprintf("Failed to delete user %s", name), err.Error())
+		return result
+	}
+	return userResult{name: name}
+}
+
+// asWarnings returns diags with every error turned into a warning, note explains what happens next.
+func asWarnings(diags diag.Diagnostics, note string) diag.Diagnostics {
+	var warnings diag.Diagnostics
+	for _, d := range diags {
+		if d.Severity() != diag.SeverityError {
+			warnings.Append(d)
+			continue
+		}
+		if withPath, ok := d.(diag.DiagnosticWithPath); ok {
+			warnings.AddAttributeWarning(withPath.Path(), d.Summary(), d.Detail()+" "+note)
+		} else {
+			warnings.AddWarning(d.Summary(), d.Detail()+" "+note)
+		}
+	}
+	return warnings
+}
+
+// collect writes the outcome of every user into users and returns the combined diagnostics. A user
+// that failed to be created is kept as planned but without an id, and its errors are reported as
+// warnings. An error would taint the resource, and replacing it recreates every user, while a user
+// without an id is created by the next apply.
+func collect(users map[string]openstackUsersUser, planned map[string]openstackUsersUser, results []userResult) diag.Diagnostics {
+	var diags diag.Diagnostics
+	for _, r := range results {
+		switch {
+		case r.notCreated:
+			failed := planned[r.name]
+			failed.Id = types.StringNull()
+			failed.Password = types.StringNull()
+			users[r.name] = failed
+			diags.Append(asWarnings(r.diags, "The user will be created by the next apply.")...)
+		case r.user == nil:
+			delete(users, r.name)
+			diags.Append(r.diags...)
+		default:
+			users[r.name] = *r.user
+			diags.Append(r.diags...)
+		}
+	}
+	return diags
+}
+
+func (c *openstackUsersResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan openstackUsersResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	plan.DomainId = types.StringValue(c.Client.domainFor(plan.DomainId))
+
+	results := forEachUser(sortedKeys(plan.Users), plan.MaxConcurrency.ValueInt64(), func(name string) userResult {
+		return c.createUser(ctx, plan.DomainId, name, plan.Users[name])
+	})
+	users := map[string]openstackUsersUser{}
+	if slices.ContainsFunc(results, func(r userResult) bool { return !r.notCreated }) {
+		resp.Diagnostics.Append(collect(users, plan.Users, results)...)
+	} else {
+		// Nothing was created, so there is nothing to keep and the failure is an error
+		for _, r := range results {
+			resp.Diagnostics.Append(r.diags...)
+		}
+	}
+	plan.Users = users
+	plan.Id = plan.DomainId
+	tflog.Trace(ctx, fmt.Sprintf("created %d users", len(users)))
+
+	// State is written even on failure or cancellation so the users that were created are tracked
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (c *openstackUsersResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state openstackUsersResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	results := forEachUser(sortedKeys(state.Users), state.MaxConcurrency.ValueInt64(), func(name string) userResult {
+		current := state.Users[name]
+		result := userResult{name: name, user: &current}
+		if current.Id.IsNull() {
+			// The user failed to be created, leaving it out of state plans its creation
+			result.user = nil
+			return result
+		}
+		user, err := c.Client.GetUserResource(ctx, state.DomainId.ValueString(), current.Id.ValueString())
+		if addCancelledError(ctx, &result.diags, "Reading user "+name, err) {
+			return result
+		}
+		if IsNotFound(err) && c.Client.FailOnUnexpectedDisappearance {
+			result.diags.AddAttributeError(
+				path.Root("users").AtMapKey(name),
+				fmt.Sprintf("Cleura user %s has been deleted outside terraform", name),
+				"It was not found in Cleura and fail_on_unexpected_disappearance is set, so it is kept in state. Unset fail_on_unexpected_disappearance to create it again.",
+			)
+			return result
+		}
+		if IsNotFound(err) {
+			// The user has been removed from outside Terraform, recreate it
+			result.user = nil
+			result.diags.AddWarning(fmt.Sprintf("Cleura user %s has been deleted outside terraform", name), "New user will be created")
+			return result
+		}
+		if err != nil {
+			result.diags.AddError(fmt.Sprintf("Error reading user %s", name), err.Error())
+			return result
+		}
+		result.user = &openstackUsersUser{
+			Id:          user.Id,
+			Description: user.Description,
+			Enabled:     user.Enabled,
+			Password:    current.Password,
+			Projects:    user.Projects,
+		}
+		return result
+	})
+	resp.Diagnostics.Append(collect(state.Users, nil, results)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (c *openstackUsersResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan openstackUsersResourceModel
+	var currentState openstackUsersResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(req.State.Get(ctx, &currentState)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if currentState.Users == nil {
+		currentState.Users = map[string]openstackUsersUser{}
+	}
+	names := map[string]bool{}
+	for name := range plan.Users {
+		names[name] = true
+	}
+	for name := range currentState.Users {
+		names[name] = true
+	}
+	results := forEachUser(sortedKeys(names), plan.MaxConcurrency.ValueInt64(), func(name string) userResult {
+		current, exists := currentState.Users[name]
+		planned, wanted := plan.Users[name]
+		switch {
+		case !wanted:
+			// A user without an id was never created, deleting it only removes it from state
+			return c.deleteUser(ctx, plan.DomainId, name, current)
+		case !exists || current.Id.IsNull():
+			return c.createUser(ctx, plan.DomainId, name, planned)
+		default:
+			return c.updateUser(ctx, plan.DomainId, name, current, planned)
+		}
+	})
+	resp.Diagnostics.Append(collect(currentState.Users, plan.Users, results)...)
+	plan.Users = currentState.Users
+
+	// State is written even on failure or cancellation so the users that were changed are tracked
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (c *openstackUsersResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state openstackUsersResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	results := forEachUser(sortedKeys(state.Users), state.MaxConcurrency.ValueInt64(), func(name string) userResult {
+		return c.deleteUser(ctx, state.DomainId, name, state.Users[name])
+	})
+	resp.Diagnostics.Append(collect(state.Users, nil, results)...)
+	if resp.Diagnostics.HasError() {
+		// Keep the users that could not be deleted in state
+		resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+	}
+}
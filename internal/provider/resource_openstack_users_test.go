@@ -0,0 +1,173 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func testUsers(users map[string]openstackUsersUser) openstackUsersResourceModel {
+	return openstackUsersResourceModel{
+		Id:             types.StringValue("d"),
+		DomainId:       types.StringValue("d"),
+		MaxConcurrency: types.Int64Value(1),
+		Users:          users,
+	}
+}
+
+func plannedUser(description string) openstackUsersUser {
+	return openstackUsersUser{
+		Id:          types.StringUnknown(),
+		Description: types.StringValue(description),
+		Enabled:     types.BoolValue(true),
+		Password:    types.StringUnknown(),
+		Projects:    membership(map[string][]string{"p1": {"member"}}).projects(),
+	}
+}
+
+func TestUsersDescriptionUpdate(t *testing.T) {
+	api := newFakeAPI()
+	h := newResourceHarness(t, NewOpenstackUsersResource(), newFakeClient(t, api))
+
+	state, err := h.Create(testUsers(map[string]openstackUsersUser{"alice": plannedUser("old")}))
+	if err != nil {
+		t.Fatalf("create: %s", err)
+	}
+	var created openstackUsersResourceModel
+	h.get(state, &created)
+	planned := created.Users["alice"]
+	planned.Description = types.StringValue("new")
+	if _, err := h.Update(state, testUsers(map[string]openstackUsersUser{"alice": planned})); err != nil {
+		t.Fatalf("update: %s", err)
+	}
+	if got := api.users["d/"+planned.Id.ValueString()].Description; got != "new" {
+		t.Errorf("description in Cleura is %q, expected new", got)
+	}
+
+	state, err = h.Read(state)
+	if err != nil {
+		t.Fatalf("read: %s", err)
+	}
+	var read openstackUsersResourceModel
+	h.get(state, &read)
+	if got := read.Users["alice"].Description.ValueString(); got != "new" {
+		t.Errorf("refreshed description is %q, expected new", got)
+	}
+}
+
+// TestUsersPartialCreate checks that a user failing to be created does not fail, and so taint,
+// the users that were created, and that the failed user is created by the next apply.
+func TestUsersPartialCreate(t *testing.T) {
+	api := newFakeAPI()
+	api.seedUser("d", "taken", "bob", nil)
+	h := newResourceHarness(t, NewOpenstackUsersResource(), newFakeClient(t, api))
+
+	plan := testUsers(map[string]openstackUsersUser{"alice": plannedUser("a"), "bob": plannedUser("b")})
+	state, err := h.Create(plan)
+	if err != nil {
+		t.Fatalf("a partial create must not fail: %s", err)
+	}
+	var created openstackUsersResourceModel
+	h.get(state, &created)
+	if created.Users["alice"].Id.IsNull() || created.Users["alice"].Password.IsNull() {
+		t.Errorf("alice was created but has no id or password in state: %+v", created.Users["alice"])
+	}
+	if !created.Users["bob"].Id.IsNull() {
+		t.Errorf("bob failed to be created but has id %s", created.Users["bob"].Id)
+	}
+
+	state, err = h.Read(state)
+	if err != nil {
+		t.Fatalf("read: %s", err)
+	}
+	var read openstackUsersResourceModel
+	h.get(state, &read)
+	if _, ok := read.Users["bob"]; ok {
+		t.Errorf("bob is still in state after refresh, so the next plan does not create it")
+	}
+
+	delete(api.users, "d/taken")
+	if _, err := h.Update(state, plan); err != nil {
+		t.Fatalf("update: %s", err)
+	}
+	names := map[string]bool{}
+	for _, user := range api.users {
+		names[user.Name] = true
+	}
+	if !names["alice"] || !names["bob"] || len(names) != 2 {
+		t.Errorf("expected alice and bob to exist once each, got %v", api.users)
+	}
+}
+
+// TestUsersRemovedBeforeCreated checks that a user whose create failed, and that has been removed
+// from the configuration since, is dropped from state without being created, as happens with
+// -refresh=false.
+func TestUsersRemovedBeforeCreated(t *testing.T) {
+	api := newFakeAPI()
+	h := newResourceHarness(t, NewOpenstackUsersResource(), newFakeClient(t, api))
+	failed := plannedUser("b")
+	failed.Id = types.StringNull()
+	failed.Password = types.StringNull()
+	prior := h.state(testUsers(map[string]openstackUsersUser{"bob": failed}))
+
+	state, err := h.Update(prior, testUsers(map[string]openstackUsersUser{}))
+	if err != nil {
+		t.Fatalf("update: %s", err)
+	}
+	if requests := api.Requests(); len(requests) != 0 {
+		t.Errorf("expected no requests, got %v", requests)
+	}
+	var updated openstackUsersResourceModel
+	h.get(state, &updated)
+	if len(updated.Users) != 0 {
+		t.Errorf("expected no users in state, got %v", updated.Users)
+	}
+}
+
+func TestUsersCreateDisabled(t *testing.T) {
+	api := newFakeAPI()
+	h := newResourceHarness(t, NewOpenstackUsersResource(), newFakeClient(t, api))
+	disabled := plannedUser("a")
+	disabled.Enabled = types.BoolValue(false)
+
+	state, err := h.Create(testUsers(map[string]openstackUsersUser{"alice": disabled}))
+	if err != nil {
+		t.Fatalf("create: %s", err)
+	}
+	var created openstackUsersResourceModel
+	h.get(state, &created)
+	if user := api.users["d/"+created.Users["alice"].Id.ValueString()]; user.Enabled {
+		t.Error("the user was created enabled in Cleura")
+	}
+	if created.Users["alice"].Enabled.ValueBool() {
+		t.Error("state records the user as enabled")
+	}
+}
+
+// TestUsersPartialUpdate checks that a user failing to be created by an update is kept in state
+// without an id, as on create, rather than being left out of the state the plan expects.
+func TestUsersPartialUpdate(t *testing.T) {
+	api := newFakeAPI()
+	h := newResourceHarness(t, NewOpenstackUsersResource(), newFakeClient(t, api))
+	state, err := h.Create(testUsers(map[string]openstackUsersUser{"alice": plannedUser("a")}))
+	if err != nil {
+		t.Fatalf("create: %s", err)
+	}
+	var created openstackUsersResourceModel
+	h.get(state, &created)
+
+	api.seedUser("d", "taken", "bob", nil)
+	state, err = h.Update(state, testUsers(map[string]openstackUsersUser{"alice": created.Users["alice"], "bob": plannedUser("b")}))
+	if err != nil {
+		t.Fatalf("a partial update must not fail: %s", err)
+	}
+	var updated openstackUsersResourceModel
+	h.get(state, &updated)
+	bob, ok := updated.Users["bob"]
+	if !ok || !bob.Id.IsNull() {
+		t.Errorf("expected bob in state without an id, got %+v", updated.Users)
+	}
+	if updated.Users["alice"].Id != created.Users["alice"].Id {
+		t.Errorf("alice changed from %+v to %+v", created.Users["alice"], updated.Users["alice"])
+	}
+}
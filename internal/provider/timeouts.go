@@ -0,0 +1,33 @@
+package provider
+
+import (
+	"context"
+	"time"
+)
+
+// Timeouts used by the user resources when the timeouts block does not set them. Creating users
+// can take minutes while Cleura provisions the Keystone entries.
+const (
+	defaultCreateTimeout = 10 * time.Minute
+	defaultReadTimeout   = 5 * time.Minute
+	defaultUpdateTimeout = 10 * time.Minute
+	defaultDeleteTimeout = 10 * time.Minute
+)
+
+type operationStartKey struct{}
+
+// withOperationTimeout returns a context that expires after timeout and remembers when the
+// operation started, so a timeout diagnostic can report how long it ran.
+func withOperationTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx = context.WithValue(ctx, operationStartKey{}, time.Now())
+	return context.WithTimeout(ctx, timeout)
+}
+
+// operationElapsed returns how long the operation started by withOperationTimeout has been running.
+func operationElapsed(ctx context.Context) time.Duration {
+	start, ok := ctx.Value(operationStartKey{}).(time.Time)
+	if !ok {
+		return 0
+	}
+	return time.Since(start).Round(time.Second)
+}
@@ -0,0 +1,61 @@
+package provider
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTOTPCode uses the SHA1 test vectors of RFC 6238, appendix B. The RFC lists 8 digit codes,
+// authenticator apps use the last 6.
+func TestTOTPCode(t *testing.T) {
+	// base32 of the ASCII secret "12345678901234567890"
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := totpCode(secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("totpCode at %d: %s", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("totpCode at %d is %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestTOTPCodeSecretFormat(t *testing.T) {
+	at := time.Unix(59, 0)
+	tests := []struct {
+		name    string
+		secret  string
+		wantErr bool
+	}{
+		{"lower case", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", false},
+		{"grouped with spaces", "GEZD GNBV GY3T QOJQ GEZD GNBV GY3T QOJQ", false},
+		{"padded", "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ====", false},
+		{"not base32", "GEZDGNBVGY3TQOJ1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := totpCode(tt.secret, at)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got code %s", got)
+				}
+				return
+			}
+			if err != nil || got != "287082" {
+				t.Errorf("got %s, %v, want 287082", got, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// authTransport adds the Cleura authentication headers to every request. When the API
+// rejects the token as expired it logs in again and replays the request exactly once. A token
+// issued outside the provider can not be renewed, so the rejection is returned to the caller.
+type authTransport struct {
+	client *CleuraClient
+	base   http.RoundTripper
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token := t.client.getToken()
+	resp, err := t.base.RoundTrip(t.authenticate(req, token))
+	if err != nil || isAuthPath(req) || t.client.externalToken || req.Context().Err() != nil || !isTokenExpired(resp) {
+		return resp, err
+	}
+	// A request with a body can only be replayed if the body can be recreated
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+	closeBody(resp)
+
+	ctx := req.Context()
+	tflog.Debug(ctx, fmt.Sprintf("Token rejected by Cleura API on %s %s, logging in again", req.Method, req.URL.Path))
+	if err := t.client.relogin(ctx, token); err != nil {
+		return nil, fmt.Errorf("token expired and login failed: %w", err)
+	}
+	replay := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		replay.Body = body
+	}
+	return t.base.RoundTrip(t.authenticate(replay, t.client.getToken()))
+}
+
+// authenticate returns a copy of the request carrying the login and token headers.
+func (t *authTransport) authenticate(req *http.Request, token string) *http.Request {
+	authenticated := req.Clone(req.Context())
+	authenticated.Header.Set("X-AUTH-LOGIN", t.client.User)
+	authenticated.Header.Set("X-AUTH-TOKEN", token)
+	return authenticated
+}
+
+// isAuthPath reports whether the request targets the token endpoints, which must never trigger a new login.
+func isAuthPath(req *http.Request) bool {
+	return strings.Contains(req.URL.Path, "/auth/v1/tokens")
+}
+
+// isTokenExpired reports whether the response was caused by an expired or revoked token.
+// Cleura answers 401 for expired tokens, a 403 is only treated as expiry when the error
+// message refers to the token since it otherwise means the user lacks permissions.
+func isTokenExpired(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		return true
+	case http.StatusForbidden:
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return false
+		}
+		apiErr := &apiError{}
+		if json.Unmarshal(body, apiErr) != nil {
+			return false
+		}
+		return strings.Contains(strings.ToLower(apiErr.Error.Message+" "+apiErr.Error.Description), "token")
+	}
+	return false
+}
+
+// timeoutTransport limits how long a single request may take, from sending it until its response
+// body has been closed. The limit applies to each attempt, so every retry gets the full timeout.
+type timeoutTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+// requestTimeoutError is returned when a request exceeded request_timeout. It is a net.Error that
+// timed out, unlike the context error of an operation that ran out of time.
+type requestTimeoutError struct {
+	timeout time.Duration
+}
+
+func (e *requestTimeoutError) Error() string {
+	return fmt.Sprintf("the Cleura API did not respond within the request_timeout of %s", e.timeout)
+}
+func (e *requestTimeoutError) Timeout() bool   { return true }
+func (e *requestTimeoutError) Temporary() bool { return false }
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	timeoutErr := &requestTimeoutError{timeout: t.timeout}
+	ctx, cancel := context.WithTimeoutCause(req.Context(), t.timeout, timeoutErr)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		if context.Cause(ctx) == timeoutErr {
+			return nil, timeoutErr
+		}
+		return nil, err
+	}
+	// The deadline must outlive RoundTrip, the body is read after it returns
+	resp.Body = &timeoutBody{ReadCloser: resp.Body, ctx: ctx, cancel: cancel, timeoutErr: timeoutErr}
+	return resp, nil
+}
+
+// timeoutBody releases the deadline of its request when closed, and reports a read that was cut
+// short by the deadline as a requestTimeoutError.
+type timeoutBody struct {
+	io.ReadCloser
+	ctx        context.Context
+	cancel     context.CancelFunc
+	timeoutErr *requestTimeoutError
+}
+
+func (b *timeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && context.Cause(b.ctx) == b.timeoutErr {
+		return n, b.timeoutErr
+	}
+	return n, err
+}
+
+func (b *timeoutBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// retryPolicy controls how often and how long the client waits before retrying a failed request.
+type retryPolicy struct {
+	MaxRetries int
+	MinWait    time.Duration
+	MaxWait    time.Duration
+}
+
+var defaultRetryPolicy = retryPolicy{
+	MaxRetries: 3,
+	MinWait:    1 * time.Second,
+	MaxWait:    30 * time.Second,
+}
+
+// retryTransport retries requests that failed with a connection error or a status code
+// that signals a transient problem. POST requests are not idempotent and are only retried
+// when the request never reached the API, or when a retry check attached to the request
+// context confirms that the object was not created.
+type retryTransport struct {
+	base   http.RoundTripper
+	policy retryPolicy
+}
+
+type retryCheckKey struct{}
+
+// retryCheck reports whether it is safe to send a non-idempotent request again.
+type retryCheck func(ctx context.Context) (bool, error)
+
+// withRetryCheck attaches a check to the context that decides whether a POST may be retried after an ambiguous failure.
+func withRetryCheck(ctx context.Context, check retryCheck) context.Context {
+	return context.WithValue(ctx, retryCheckKey{}, check)
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(ctx)
+			attemptReq.Body = body
+		}
+		resp, err := t.base.RoundTrip(attemptReq)
+		// A cancelled request is never retried, the caller is no longer waiting for it
+		if ctx.Err() != nil || attempt >= t.policy.MaxRetries || !t.shouldRetry(req, resp, err) {
+			return resp, err
+		}
+		wait := t.backoff(attempt, resp)
+		if resp != nil {
+			closeBody(resp)
+		}
+		tflog.Debug(ctx, fmt.Sprintf("Retrying %s %s in %s (attempt %d of %d)", req.Method, req.URL.Path, wait, attempt+1, t.policy.MaxRetries))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (t *retryTransport) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		if isConnectError(err) {
+			return true
+		}
+		if !isTransientError(err) {
+			return false
+		}
+		return isIdempotent(req.Method) || isSafeToRetry(req)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		// Rate limited requests are rejected before they are processed
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return isIdempotent(req.Method) || isSafeToRetry(req)
+	}
+	return false
+}
+
+// backoff returns how long to wait before the next attempt. Retry-After is honoured when
+// present, up to MaxWait, otherwise an exponential backoff with full jitter between MinWait and
+// MaxWait is used.
+func (t *retryTransport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			// A server asking for hours would otherwise stall the apply until it times out
+			return min(wait, t.policy.MaxWait)
+		}
+	}
+	ceiling := t.policy.MinWait << attempt
+	if ceiling > t.policy.MaxWait || ceiling <= 0 {
+		ceiling = t.policy.MaxWait
+	}
+	if ceiling <= t.policy.MinWait {
+		return t.policy.MinWait
+	}
+	return t.policy.MinWait + time.Duration(rand.Int63n(int64(ceiling-t.policy.MinWait)))
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// isSafeToRetry runs the retry check attached to the request, if any.
+func isSafeToRetry(req *http.Request) bool {
+	check, ok := req.Context().Value(retryCheckKey{}).(retryCheck)
+	if !ok {
+		return false
+	}
+	safe, err := check(req.Context())
+	if err != nil {
+		tflog.Debug(req.Context(), fmt.Sprintf("Retry check for %s %s failed, error: %s", req.Method, req.URL.Path, err.Error()))
+		return false
+	}
+	return safe
+}
+
+// isConnectError reports whether the request failed before a connection to the API was established.
+func isConnectError(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// isTransientError reports whether an established connection broke while the request was in flight.
+func isTransientError(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// configuredClients holds every client created by the provider so their tokens can be revoked on shutdown.
+var (
+	configuredClientsMu sync.Mutex
+	configuredClients   []*CleuraClient
+)
+
+func trackClient(c *CleuraClient) {
+	configuredClientsMu.Lock()
+	defer configuredClientsMu.Unlock()
+	configuredClients = append(configuredClients, c)
+}
+
+// revokeTimeout bounds the revocation of all tokens. Terraform kills a provider that has not exited
+// two seconds after it was asked to stop.
+const revokeTimeout = 1500 * time.Millisecond
+
+// Shutdown revokes the tokens of all clients configured by the provider. It is called once the provider
+// server has stopped or the process is terminated. Revocation is best effort: a provider that is killed
+// or can not reach the API in time leaves its tokens to expire on their own.
+func Shutdown(ctx context.Context) {
+	configuredClientsMu.Lock()
+	defer configuredClientsMu.Unlock()
+	ctx, cancel := context.WithTimeout(ctx, revokeTimeout)
+	defer cancel()
+	for _, c := range configuredClients {
+		if err := c.RevokeToken(ctx); err != nil {
+			tflog.Warn(ctx, fmt.Sprintf("Failed to revoke Cleura token, error: %s", err.Error()))
+		}
+	}
+	configuredClients = nil
+}
@@ -0,0 +1,165 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExternalTokenIsNotRenewed(t *testing.T) {
+	var logins atomic.Int32
+	client, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/auth/v1/tokens" {
+			logins.Add(1)
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	client.useToken("expired")
+	err := client.ValidateToken(context.Background())
+	var apiErr *CleuraAPIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected a 401 CleuraAPIError, got: %v", err)
+	}
+	if n := logins.Load(); n != 0 {
+		t.Errorf("expected no login for an external token, got %d", n)
+	}
+}
+
+// TestShutdownIsBounded checks that a stalled revocation does not keep the provider from exiting
+// before Terraform kills it.
+func TestShutdownIsBounded(t *testing.T) {
+	stalled := make(chan struct{})
+	defer close(stalled)
+	var revoked atomic.Int32
+	revoking, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		revoked.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	stalling, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-stalled
+	}))
+	trackClient(revoking)
+	trackClient(stalling)
+
+	start := time.Now()
+	Shutdown(context.Background())
+	if elapsed := time.Since(start); elapsed > revokeTimeout+500*time.Millisecond {
+		t.Errorf("shutdown took %s, expected at most %s", elapsed, revokeTimeout)
+	}
+	if revoked.Load() != 1 || revoking.getToken() != "" {
+		t.Errorf("expected the token to be revoked once, got %d revocations", revoked.Load())
+	}
+	if len(configuredClients) != 0 {
+		t.Errorf("expected no clients to be tracked after shutdown, got %d", len(configuredClients))
+	}
+}
+
+func TestBackoffCapsRetryAfter(t *testing.T) {
+	transport := &retryTransport{policy: retryPolicy{MaxRetries: 3, MinWait: time.Second, MaxWait: 30 * time.Second}}
+	tests := []struct {
+		name       string
+		retryAfter string
+		want       time.Duration
+	}{
+		{"zero", "0", 0},
+		{"below max", "5", 5 * time.Second},
+		{"at max", "30", 30 * time.Second},
+		{"above max", "3600", 30 * time.Second},
+		{"date above max", time.Now().Add(24 * time.Hour).UTC().Format(http.TimeFormat), 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{"Retry-After": []string{tt.retryAfter}}}
+			if got := transport.backoff(0, resp); got != tt.want {
+				t.Errorf("backoff is %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryAfterIsCappedAtMaxWait(t *testing.T) {
+	var attempts atomic.Int32
+	client, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	client.Client.Transport.(*authTransport).base.(*retryTransport).policy = retryPolicy{MaxRetries: 1, MinWait: 0, MaxWait: 50 * time.Millisecond}
+
+	start := time.Now()
+	if err := client.DeleteProject(context.Background(), "provider-domain", "p1"); err != nil {
+		t.Fatalf("delete: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("request took %s, Retry-After was not capped", elapsed)
+	}
+	if n := attempts.Load(); n != 2 {
+		t.Errorf("expected 2 attempts, got %d", n)
+	}
+}
+
+// TestCreateRetriedOnlyIfNotCreated answers the first create with a 503, after creating the object
+// or not, and checks that the create is sent again only when the lookup does not find the object.
+func TestCreateRetriedOnlyIfNotCreated(t *testing.T) {
+	creates := []struct {
+		name   string
+		path   string
+		create func(client *CleuraClient) error
+	}{
+		{"openstack user", "/accesscontrol/v1/openstack/d/users", func(client *CleuraClient) error {
+			_, err := client.CreateUser(context.Background(), testUser(true, "Secret-Password-1", nil))
+			return err
+		}},
+		{"ccp user", "/accesscontrol/v1/users", func(client *CleuraClient) error {
+			_, err := client.CreateCCPUser(context.Background(), ccpUserWithProjects("project", "p1"))
+			return err
+		}},
+	}
+	for _, c := range creates {
+		for _, created := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s created %t", c.name, created), func(t *testing.T) {
+				api := newFakeAPI()
+				handler := api.handler()
+				var posts atomic.Int32
+				client, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.Method == http.MethodPost && r.URL.Path == c.path && posts.Add(1) == 1 {
+						if created {
+							handler.ServeHTTP(httptest.NewRecorder(), r)
+						}
+						w.WriteHeader(http.StatusServiceUnavailable)
+						return
+					}
+					handler.ServeHTTP(w, r)
+				}))
+				client.Client.Transport.(*authTransport).base.(*retryTransport).policy = retryPolicy{MaxRetries: 1, MinWait: 0, MaxWait: 10 * time.Millisecond}
+
+				err := c.create(client)
+				var apiErr *CleuraAPIError
+				switch {
+				case created && (!errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable):
+					t.Errorf("expected the 503 to be returned, got: %v", err)
+				case !created && err != nil:
+					t.Errorf("expected the retried create to succeed, got: %s", err)
+				}
+				want := int32(2)
+				if created {
+					want = 1
+				}
+				if n := posts.Load(); n != want {
+					t.Errorf("sent the create %d times, want %d", n, want)
+				}
+				if n := len(api.users) + len(api.ccpUsers); n != 1 {
+					t.Errorf("expected one user to exist, got %d", n)
+				}
+			})
+		}
+	}
+}
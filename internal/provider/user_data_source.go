@@ -41,8 +41,11 @@ func (c *cleuraUserDataSource) Metadata(_ context.Context, req datasource.Metada
 func (c *cleuraUserDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
 	var userData openstackUserDatasourceModel
 	resp.Diagnostics.Append(req.Config.Get(ctx, &userData)...)
-	result, err := c.Client.GetUser(ctx, userData.Id.ValueString())
+	result, err := c.Client.GetUser(ctx, c.Client.domainFor(userData.DomainId), userData.Id.ValueString())
 	if err != nil {
+		if addCancelledError(ctx, &resp.Diagnostics, "Reading user "+userData.Id.ValueString(), err) {
+			return
+		}
 		resp.Diagnostics.AddError(
 			"Failed to get user",
 			err.Error(),
@@ -66,7 +69,9 @@ func (c *cleuraUserDataSource) Schema(_ context.Context, _ datasource.SchemaRequ
 				Computed: true,
 			},
 			"domain_id": schema.StringAttribute{
-				Computed: true,
+				Description: "Domain of the user. Defaults to the domain_id of the provider.",
+				Optional:    true,
+				Computed:    true,
 			},
 			"default_project_id": schema.StringAttribute{
 				Computed: true,
@@ -7,6 +7,9 @@ import (
 	"context"
 	"flag"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 	"terraform-provider-cleuracloud/internal/provider"
 
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
@@ -20,7 +23,7 @@ import (
 
 // Run the docs generation tool, check its repository for more information on how it works and how docs
 // can be customized.
-//go:generate go run github.com/hashicorp/terraform-plugin-docs/cmd/tfplugindocs generate -provider-name hashicups
+//go:generate go run github.com/hashicorp/terraform-plugin-docs/cmd/tfplugindocs generate -provider-name cleuracloud
 
 var (
 	version string = "dev"
@@ -38,7 +41,18 @@ func main() {
 		Debug:   debug,
 	}
 
+	// Terraform normally stops the provider gracefully and Serve returns, but a terminated provider
+	// revokes its tokens too
+	terminated := make(chan os.Signal, 1)
+	signal.Notify(terminated, syscall.SIGTERM)
+	go func() {
+		<-terminated
+		provider.Shutdown(context.Background())
+		os.Exit(1)
+	}()
+
 	err := providerserver.Serve(context.Background(), provider.New(version), opts)
+	provider.Shutdown(context.Background())
 
 	if err != nil {
 		log.Fatal(err.Error())